@@ -1,4 +1,4 @@
 clear
 rm editor2
-go build -o editor2 ./src/*
+go build -o editor2 ./src
 ./editor2
\ No newline at end of file
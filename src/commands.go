@@ -0,0 +1,380 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Every editor action is a command with a stable ID, a title for the palette /
+// menus and a default keybinding. Menus, the command palette and keyboard
+// shortcuts (see keymap.go) all go through runCommand.
+type Command struct {
+	ID         string
+	Title      string
+	Keybinding string // default binding, e.g. "Ctrl+Shift+P" or "Ctrl+K Ctrl+C", empty for none
+	Run        func()
+	Repeat     bool // runs again while its key is held, for cursor movement
+}
+
+var commands []*Command
+var commandsByID = map[string]*Command{}
+
+func registerCommand(c *Command) {
+	if old, ok := commandsByID[c.ID]; ok {
+		*old = *c
+		return
+	}
+	commands = append(commands, c)
+	commandsByID[c.ID] = c
+}
+
+func runCommand(id string) {
+	c, ok := commandsByID[id]
+	if !ok {
+		notifyWarning("Unknown command: %s", id)
+		return
+	}
+	c.Run()
+}
+
+func registerDefaultCommands() {
+	for _, c := range []*Command{
+		{ID: "file.new", Title: "File: New", Keybinding: "Ctrl+N", Run: newBuffer},
+		{ID: "file.open", Title: "File: Open...", Keybinding: "Ctrl+O", Run: openFileModal},
+		{ID: "file.quickOpen", Title: "File: Quick Open", Keybinding: "Ctrl+P", Run: openQuickOpen},
+		{ID: "file.reindex", Title: "File: Rebuild Quick Open Index", Run: projectIndex.refresh},
+		{ID: "file.openPicker", Title: "File: Open Pick", Run: toggleFilePicker},
+		{ID: "file.save", Title: "File: Save", Keybinding: "Ctrl+S", Run: saveCurrentFile},
+		{ID: "file.saveAs", Title: "File: Save As...", Keybinding: "Ctrl+Shift+S", Run: openSaveAsModal},
+		{ID: "app.quit", Title: "Quit", Keybinding: "Ctrl+Q", Run: func() { os.Exit(1) }},
+
+		{ID: "edit.undo", Title: "Edit: Undo", Keybinding: "Ctrl+Z", Run: undo},
+		{ID: "edit.redo", Title: "Edit: Redo", Keybinding: "Ctrl+Shift+Z", Run: redo},
+		{ID: "edit.cut", Title: "Edit: Cut", Keybinding: "Ctrl+X", Run: cutSelection},
+		{ID: "edit.copy", Title: "Edit: Copy", Keybinding: "Ctrl+C", Run: copySelection},
+		{ID: "edit.paste", Title: "Edit: Paste", Keybinding: "Ctrl+V", Run: pasteClipboard},
+		{ID: "edit.selectAll", Title: "Edit: Select All", Keybinding: "Ctrl+A", Run: selectAll},
+
+		{ID: "search.find", Title: "Search: Find Word / Selection", Keybinding: "Ctrl+F", Run: searchFromCursor},
+		{ID: "search.findNext", Title: "Search: Find Next", Keybinding: "F3", Run: findNext},
+
+		{ID: "selection.line", Title: "Selection: Select Line", Run: func() { selectLine(cursor.y) }},
+		{ID: "selection.word", Title: "Selection: Select Word", Run: selectWordAtCursor},
+		{ID: "selection.clear", Title: "Selection: Clear", Run: func() { selection.reset() }},
+
+		{ID: "cursor.left", Title: "Cursor: Left", Keybinding: "Left", Run: cursorLeft, Repeat: true},
+		{ID: "cursor.right", Title: "Cursor: Right", Keybinding: "Right", Run: cursorRight, Repeat: true},
+		{ID: "cursor.up", Title: "Cursor: Up", Keybinding: "Up", Run: cursorUp, Repeat: true},
+		{ID: "cursor.down", Title: "Cursor: Down", Keybinding: "Down", Run: cursorDown, Repeat: true},
+		{ID: "cursor.selectLeft", Title: "Cursor: Select Left", Keybinding: "Shift+Left", Run: cursorSelectLeft, Repeat: true},
+		{ID: "cursor.selectRight", Title: "Cursor: Select Right", Keybinding: "Shift+Right", Run: cursorSelectRight, Repeat: true},
+		{ID: "cursor.selectUp", Title: "Cursor: Select Up", Keybinding: "Shift+Up", Run: cursorSelectUp, Repeat: true},
+		{ID: "cursor.selectDown", Title: "Cursor: Select Down", Keybinding: "Shift+Down", Run: cursorSelectDown, Repeat: true},
+		{ID: "cursor.pageUp", Title: "Cursor: Page Up", Keybinding: "PageUp", Run: cursorPageUp},
+		{ID: "cursor.pageDown", Title: "Cursor: Page Down", Keybinding: "PageDown", Run: cursorPageDown},
+		{ID: "cursor.lineStart", Title: "Cursor: Start of Line", Keybinding: "Home", Run: cursorLineStart},
+		{ID: "cursor.lineEnd", Title: "Cursor: End of Line", Keybinding: "End", Run: cursorLineEnd},
+		{ID: "edit.tab", Title: "Edit: Insert Tab", Keybinding: "Tab", Run: insertTab},
+
+		{ID: "go.documentStart", Title: "Go: Start of Document", Keybinding: "Ctrl+Home", Run: goToDocumentStart},
+		{ID: "go.documentEnd", Title: "Go: End of Document", Keybinding: "Ctrl+End", Run: goToDocumentEnd},
+
+		{ID: "view.commandPalette", Title: "View: Command Palette", Keybinding: "Ctrl+Shift+P", Run: openCommandPalette},
+		{ID: "view.lineNumbers", Title: "View: Cycle Line Numbers", Keybinding: "Ctrl+L", Run: cycleLineNumberMode},
+		{ID: "view.wordWrap", Title: "View: Toggle Word Wrap", Keybinding: "Alt+Z", Run: toggleWordWrap},
+		{ID: "view.whitespace", Title: "View: Toggle Whitespace", Keybinding: "Alt+W", Run: toggleShowWhitespace},
+		{ID: "view.sidebar", Title: "View: Toggle Sidebar", Keybinding: "Ctrl+B", Run: toggleSidebar},
+		{ID: "view.splitRight", Title: "View: Split Pane Right", Keybinding: "Ctrl+\\", Run: splitPaneRight},
+		{ID: "view.splitDown", Title: "View: Split Pane Down", Keybinding: "Ctrl+Shift+\\", Run: splitPaneDown},
+		{ID: "view.closePane", Title: "View: Close Pane", Keybinding: "Ctrl+W", Run: closePane},
+		{ID: "view.focusNextPane", Title: "View: Focus Next Pane", Keybinding: "Ctrl+K Ctrl+Right", Run: focusNextPane},
+		{ID: "view.focusPreviousPane", Title: "View: Focus Previous Pane", Keybinding: "Ctrl+K Ctrl+Left", Run: focusPreviousPane},
+		{ID: "view.messages", Title: "View: Messages", Keybinding: "Ctrl+Shift+M", Run: openMessagesPanel},
+		{ID: "view.minimap", Title: "View: Toggle Minimap", Keybinding: "Alt+M", Run: toggleMinimap},
+
+		{ID: "help.keybindings", Title: "Help: Keyboard Shortcuts", Run: showKeybindings},
+		{ID: "help.reloadKeymap", Title: "Help: Reload Keymap", Run: reloadKeymap},
+
+		{ID: "notes.panel", Title: "Notes: Show Notes", Run: toggleNotesPanel},
+		{ID: "notes.create", Title: "Notes: Create Note", Run: openCreateNoteModal},
+		{ID: "notes.delete", Title: "Notes: Delete Current Note", Run: deleteCurrentNote},
+		{ID: "notes.search", Title: "Notes: Search Notes", Keybinding: "Ctrl+Shift+F", Run: openNotesSearch},
+		{ID: "notes.tags", Title: "Notes: Browse Tags", Run: browseNoteTags},
+		{ID: "notes.followLink", Title: "Notes: Follow Link", Keybinding: "Ctrl+Enter", Run: followLinkAtCursor},
+		{ID: "notes.backlinks", Title: "Notes: Show Backlinks", Keybinding: "Ctrl+Shift+B", Run: openBacklinks},
+		{ID: "notes.back", Title: "Notes: Back", Keybinding: "Alt+Left", Run: noteHistoryBack},
+		{ID: "notes.forward", Title: "Notes: Forward", Keybinding: "Alt+Right", Run: noteHistoryForward},
+	} {
+		registerCommand(c)
+	}
+}
+
+// one command per line number mode, used by the View > Line Numbers submenu
+func registerLineNumberCommands() {
+	for _, mode := range lineNumberModes {
+		registerCommand(&Command{
+			ID:    "view.lineNumbers." + mode,
+			Title: "View: Line Numbers " + strings.ToUpper(mode[:1]) + mode[1:],
+			Run:   func() { setLineNumberMode(mode) },
+		})
+	}
+}
+
+// one command per theme, refreshed whenever the themes are (re)loaded
+func registerThemeCommands() {
+	for _, name := range themeNames() {
+		registerCommand(&Command{
+			ID:    "view.theme." + strings.ToLower(name),
+			Title: "View: Theme " + name,
+			Run:   func() { selectTheme(name) },
+		})
+	}
+}
+
+// ------------------------------------------------------------------------------------
+// Actions
+
+func newBuffer() {
+	clearTextGrid()
+	cursor.reset()
+	resetUndoRedoStacks()
+}
+
+func okCancelButtons(ok string) []DialogButton {
+	return []DialogButton{
+		{Label: "Cancel", Role: ButtonCancel},
+		{Label: ok, Role: ButtonDefault},
+	}
+}
+
+func openFileModal() {
+	pushDialog(&Dialog{
+		Title:   "Open File",
+		Fields:  []*DialogField{{Label: "File path", Box: newDialogInput("path", "")}},
+		Buttons: okCancelButtons("Open"),
+		Validate: func(d *Dialog) string {
+			path := d.Value(0)
+			if path == "" {
+				return "Enter the path of a file"
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				return err.Error()
+			}
+			if info.IsDir() {
+				return path + " is a folder"
+			}
+			return ""
+		},
+		OnResult: func(d *Dialog, button string) error {
+			openFileInEditor(d.Value(0))
+			return nil
+		},
+	})
+}
+
+func openSaveAsModal() {
+	name := ""
+	if currentFile != "Untitled" {
+		name = currentFile
+	}
+	pushDialog(&Dialog{
+		Title:   "Save As",
+		Fields:  []*DialogField{{Label: "File path", Box: newDialogInput("path", name)}},
+		Buttons: okCancelButtons("Save"),
+		Validate: func(d *Dialog) string {
+			path := d.Value(0)
+			if path == "" {
+				return "Enter a file name"
+			}
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				return path + " is a folder"
+			}
+			return ""
+		},
+		OnResult: func(d *Dialog, button string) error {
+			path := d.Value(0)
+			if _, err := os.Stat(path); err != nil || path == currentFile {
+				return saveBufferAs(path)
+			}
+			// ask before replacing another file, Save As stays open below
+			confirmDialog("Replace File", filepath.Base(path)+" already exists. Do you want to replace it?", "Replace", true, func() {
+				if err := saveBufferAs(path); err != nil {
+					d.Error = err.Error()
+					return
+				}
+				d.Close()
+			})
+			return errKeepDialog
+		},
+	})
+}
+
+func saveBufferAs(path string) error {
+	if err := saveTextGridToFile(path); err != nil {
+		return err
+	}
+	currentFile = path
+	editorStatus = "Saved " + path
+	return nil
+}
+
+// template choice of Create Note that saves the current buffer as the note
+const noteTemplateNone = "(current buffer)"
+
+func openCreateNoteModal() {
+	templates := append([]string{noteTemplateNone}, noteTemplateNames()...)
+	pushDialog(&Dialog{
+		Title:   "Create Note",
+		Message: "Creates today's note as " + notePattern() + " in " + currentNotebook().Name + ", from a template or with the current buffer.",
+		Fields: []*DialogField{
+			{Label: "Title (optional)", Box: newDialogInput("note", "")},
+			newDialogChoice("Template", templates, settings.NoteTemplate),
+		},
+		Buttons: okCancelButtons("Create"),
+		OnResult: func(d *Dialog, button string) error {
+			template := d.Value(1)
+			if template == noteTemplateNone {
+				template = ""
+			}
+			if template != settings.NoteTemplate {
+				settings.NoteTemplate = template
+				if err := saveSetting("noteTemplate", template); err != nil {
+					notifyError("Could not save config.json: %v", err)
+				}
+			}
+			path, err := createNote(d.Value(0), template)
+			if err != nil {
+				return err
+			}
+			editorStatus = "Created note " + path
+			return nil
+		},
+	})
+}
+
+// loads a file into the editor, errors are reported as notifications
+func openFileInEditor(path string) {
+	code, err := loadFileIntoTextGrid(path)
+	if err != nil {
+		notifyError("Could not open %s: %v", path, err)
+		if code != -1 {
+			// partly read, keep the text but not the name
+			currentFile = "Untitled"
+			resetUndoRedoStacks()
+		}
+		return
+	}
+	resetUndoRedoStacks()
+	editorStatus = "Loaded: " + path
+	ensureCursorVisible(cursor)
+}
+
+func saveCurrentFile() {
+	if currentFile == "Untitled" {
+		openSaveAsModal()
+		return
+	}
+	if err := saveTextGridToFile(currentFile); err != nil {
+		notifyError("Could not save %s: %v", currentFile, err)
+		return
+	}
+	editorStatus = "Saved " + currentFile
+}
+
+func toggleFilePicker() {
+	if ui.ShowFilePicker {
+		closeFilePicker()
+	} else {
+		openFilePicker()
+	}
+}
+
+func toggleNotesPanel() {
+	if ui.ShowNotesPanel {
+		ui.ShowNotesPanel = false
+	} else {
+		showNotesFolder("")
+		ui.ShowNotesPanel = true
+	}
+}
+
+func deleteCurrentNote() {
+	if isNote(currentFile) {
+		if err := deleteFile(currentFile); err != nil {
+			notifyError("Could not delete note: %v", err)
+			return
+		}
+		clearTextGrid()
+		resetUndoRedoStacks()
+	} else {
+		editorStatus = "Not a Note"
+	}
+}
+
+func copySelection() {
+	if !selection.Active {
+		return
+	}
+	editorClipboard = getSelectedText()
+	writeClipboard(editorClipboard)
+	selection.reset()
+}
+
+func cutSelection() {
+	if !selection.Active {
+		return
+	}
+	editorClipboard = getSelectedText()
+	writeClipboard(editorClipboard)
+	cursor.backspace()
+	selection.reset()
+	ensureCursorVisible(cursor)
+}
+
+func pasteClipboard() {
+	if editorClipboard == "" {
+		return
+	}
+	ensureGridCapacityForPaste(cursor.x, cursor.y, editorClipboard)
+	undoStack = append(undoStack, takeSnapshot())
+	redoStack = nil
+
+	insertStringAtCursor(editorClipboard)
+	selection.Active = false
+	ensureCursorVisible(cursor)
+}
+
+func selectAll() {
+	selection.Active = true
+	selection.StartX = 0
+	selection.StartY = 0
+	selection.EndY = editorRows - 1
+	selection.EndX = editorCols - 1
+}
+
+// selects the word under (or just left of) the cursor
+func selectWordAtCursor() {
+	start, end, ok := wordBounds(cursor.x, cursor.y)
+	if !ok {
+		return
+	}
+	selection.reset()
+	selection.Active = true
+	selection.StartX, selection.StartY = start, cursor.y
+	selection.EndX, selection.EndY = end-1, cursor.y
+	cursor.x = end
+	ensureCursorVisible(cursor)
+}
+
+func goToDocumentStart() {
+	cursor.reset()
+	ensureCursorVisible(cursor)
+}
+
+func goToDocumentEnd() {
+	cursor.y = usedRows - 1
+	cursor.clampXToLineEnd()
+	ensureCursorVisible(cursor)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// returns the editor config folder ($XDG_CONFIG_HOME/editor2 or ~/.config/editor2)
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "editor2")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".editor2"
+	}
+	return filepath.Join(home, ".config", "editor2")
+}
+
+// returns path of a file/folder inside the config folder
+func configPath(name ...string) string {
+	return filepath.Join(append([]string{configDir()}, name...)...)
+}
+
+// user settings, read from <config>/config.json. missing fields keep their defaults
+type Settings struct {
+	LineNumbers string `json:"lineNumbers"` // "off", "absolute", "relative" or "hybrid"
+	WordWrap    bool   `json:"wordWrap"`
+	WrapColumn  int    `json:"wrapColumn"` // 0 wraps at the viewport width
+
+	Whitespace WhitespaceSettings `json:"whitespace"`
+
+	Minimap      bool `json:"minimap"`      // toggled with Alt+M
+	MinimapWidth int  `json:"minimapWidth"` // in pixels, one per character
+
+	Theme string `json:"theme"` // name of a bundled or <config>/themes theme
+
+	Ignore []string `json:"ignore"` // .gitignore style patterns hidden from Quick Open
+
+	Sidebar      bool `json:"sidebar"`      // toggled with Ctrl+B
+	SidebarWidth int  `json:"sidebarWidth"` // in pixels, changed by dragging its edge
+
+	NotesRoot string     `json:"notesRoot"` // folder of the default notebook, see notes.go
+	Notebooks []Notebook `json:"notebooks"` // more named notebooks
+	Notebook  string     `json:"notebook"`  // name of the notebook in use
+
+	NotePattern  string `json:"notePattern"`  // where Create Note puts notes, "YYYY/MM/YYYY-MM-DD.md"
+	NoteTemplate string `json:"noteTemplate"` // last template picked in Create Note, "" for none
+}
+
+type WhitespaceSettings struct {
+	Show         bool   `json:"show"`         // toggled with Alt+W
+	Spaces       bool   `json:"spaces"`       // dots for spaces
+	Tabs         bool   `json:"tabs"`         // arrows for tabs
+	LineEnds     bool   `json:"lineEnds"`     // pilcrow at the end of lines
+	Trailing     bool   `json:"trailing"`     // highlight trailing whitespace
+	ControlChars string `json:"controlChars"` // "caret" (^M), "hex" (1b) or "block"
+}
+
+var settings = Settings{
+	LineNumbers:  "absolute",
+	MinimapWidth: 80,
+	Whitespace: WhitespaceSettings{
+		Spaces:       true,
+		Tabs:         true,
+		LineEnds:     true,
+		Trailing:     true,
+		ControlChars: "caret",
+	},
+}
+
+// set when config.json could not be parsed, saveSetting leaves the file alone then
+var settingsBroken bool
+
+func loadSettings() {
+	data, err := os.ReadFile(configPath("config.json"))
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		settingsBroken = true
+		notifyError("Could not read config.json: %v", err)
+	}
+}
+
+// stores one setting in config.json (used when a choice is made in the menus). The
+// other keys of the file are kept as they are, defaults and runtime toggles are not
+// written out.
+func saveSetting(key string, value any) error {
+	if settingsBroken {
+		return errors.New("config.json has errors, fix them first")
+	}
+	path := configPath("config.json")
+	fields := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &fields)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if fields == nil { // the file was "null"
+		fields = map[string]json.RawMessage{}
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	fields[key] = raw
+	data, err = json.MarshalIndent(fields, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveSetting(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string // "" for no config.json
+		broken bool
+		want   string
+	}{
+		{"no file", "", false, "{\n\t\"theme\": \"Light\"\n}"},
+		{"other keys kept", `{"wordWrap": true, "theme": "Dark", "custom": [1, 2]}`, false,
+			"{\n\t\"custom\": [\n\t\t1,\n\t\t2\n\t],\n\t\"theme\": \"Light\",\n\t\"wordWrap\": true\n}"},
+		{"parse error", `{"theme": "Dark",}`, true, `{"theme": "Dark",}`},
+	}
+	for _, tt := range tests {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		path := configPath("config.json")
+		if tt.before != "" {
+			os.MkdirAll(filepath.Dir(path), 0755)
+			os.WriteFile(path, []byte(tt.before), 0644)
+		}
+		settingsBroken = tt.broken
+
+		err := saveSetting("theme", "Light")
+		if (err != nil) != tt.broken {
+			t.Errorf("%s: saveSetting() error = %v", tt.name, err)
+		}
+		data, _ := os.ReadFile(path)
+		if string(data) != tt.want {
+			t.Errorf("%s: config.json = %q, want %q", tt.name, data, tt.want)
+		}
+	}
+	settingsBroken = false
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Context menus: a menu panel opened at the mouse by a right-click, on the modal
+// stack so nothing below gets input while it is open. Up/Down move, Enter runs,
+// the mnemonic letter picks an item, Esc or a click outside closes. Items run a
+// command and show its keybinding like the menu bar, or run their own action
+// with the shortcut of the list they belong to.
+
+type ContextMenu struct {
+	panel    menuPanel
+	selected int
+}
+
+func openContextMenu(items []MenuItem) *ContextMenu {
+	m := &ContextMenu{panel: newMenuPanel(items, int(rl.GetMouseX()), int(rl.GetMouseY())), selected: -1}
+	if m.panel.y+m.panel.h > windowHeight {
+		m.panel.y = max(0, windowHeight-m.panel.h)
+	}
+	pushModal(m)
+	return m
+}
+
+func (m *ContextMenu) activate(i int) {
+	item := m.panel.items[i]
+	if !item.selectable() {
+		return
+	}
+	closeModal(m)
+	if item.Run != nil {
+		item.Run()
+	} else {
+		runCommand(item.Command)
+	}
+}
+
+func (m *ContextMenu) drawModal(top bool) {
+	if top {
+		m.handleInput()
+		if !isModalOpen(m) {
+			return
+		}
+	}
+	drawMenuPanel(m.panel, m.selected)
+}
+
+func (m *ContextMenu) handleInput() {
+	p := m.panel
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+	over := mouseX >= p.x && mouseX < p.x+p.w && mouseY >= p.y && mouseY < p.y+p.h
+	if over && (rl.GetMouseDelta().X != 0 || rl.GetMouseDelta().Y != 0) {
+		if i := p.itemAt(mouseX, mouseY); i != -1 && p.items[i].selectable() {
+			m.selected = i
+		}
+	}
+
+	pressed := rl.IsMouseButtonPressed(rl.MouseLeftButton)
+	switch {
+	case pressed && over:
+		if i := p.itemAt(mouseX, mouseY); i != -1 {
+			m.activate(i)
+		}
+	case pressed || rl.IsMouseButtonPressed(rl.MouseRightButton) || rl.IsMouseButtonPressed(rl.MouseMiddleButton):
+		// click outside
+		closeModal(m)
+	case rl.IsKeyPressed(rl.KeyEscape):
+		closeModal(m)
+	case rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown):
+		m.selected = nextMenuItem(p.items, m.selected, 1)
+	case rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp):
+		if m.selected == -1 {
+			m.selected = len(p.items)
+		}
+		m.selected = nextMenuItem(p.items, m.selected, -1)
+	case rl.IsKeyPressed(rl.KeyEnter) || rl.IsKeyPressed(rl.KeySpace):
+		if m.selected >= 0 && m.selected < len(p.items) {
+			m.activate(m.selected)
+		}
+	default:
+		for i, item := range p.items {
+			_, mnemonic, _ := menuLabel(item.Label)
+			if mnemonic >= 'a' && mnemonic <= 'z' && rl.IsKeyPressed(letterKey(mnemonic)) {
+				m.activate(i)
+				break
+			}
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------
+
+// right-click in the text: a click outside the selection moves the cursor first
+func openTextContextMenu(x, y int) {
+	if !selection.Active || !isCellSelected(x, y) {
+		selection.reset()
+		cursor.x, cursor.y = x, y
+	}
+	findLabel := "&Find Word"
+	if selection.Active {
+		findLabel = "&Find Selection"
+	}
+	var items []MenuItem
+	if l, ok := wikiLinkAt(x, y); ok {
+		items = append(items,
+			MenuItem{Label: "F&ollow Link", Run: func() { followWikiLink(l.target) }, Shortcut: "Ctrl+Click"},
+			menuSeparator(),
+		)
+	}
+	openContextMenu(append(items, []MenuItem{
+		{Label: "Cu&t", Command: "edit.cut", Disabled: !selection.Active},
+		{Label: "&Copy", Command: "edit.copy", Disabled: !selection.Active},
+		{Label: "&Paste", Command: "edit.paste", Disabled: editorClipboard == ""},
+		menuSeparator(),
+		{Label: "Select &All", Command: "edit.selectAll"},
+		{Label: "Select &Word", Command: "selection.word"},
+		{Label: "Select &Line", Command: "selection.line"},
+		menuSeparator(),
+		{Label: findLabel, Command: "search.find"},
+	}...))
+}
+
+// actions on an entry of the file picker, the same as its keys
+func pickerContextItems(entry *FileEntry) []MenuItem {
+	items := []MenuItem{}
+	if entry != nil {
+		path := filepath.Join(ui.CurrentPath, entry.Name)
+		e := *entry
+		items = append(items,
+			MenuItem{Label: "&Open", Run: func() { pickerActivate(e) }, Shortcut: "Enter"},
+			MenuItem{Label: "&Rename", Run: func() { pickerStartAction("rename") }, Shortcut: "F2"},
+			MenuItem{Label: "D&uplicate", Run: func() { pickerStartAction("duplicate") }, Shortcut: "Ctrl+D"},
+			MenuItem{Label: "&Move...", Run: func() { pickerStartAction("move") }, Shortcut: "Ctrl+M"},
+			MenuItem{Label: "&Delete", Run: func() { pickerStartAction("delete") }, Shortcut: "Del"},
+			menuSeparator(),
+			MenuItem{Label: "Re&veal in File Manager", Run: func() { revealInFileManager(path) }},
+			menuSeparator(),
+		)
+	}
+	return append(items,
+		MenuItem{Label: "New &File", Run: func() { pickerStartAction("newFile") }, Shortcut: "Ctrl+N"},
+		MenuItem{Label: "New Fol&der", Run: func() { pickerStartAction("newFolder") }, Shortcut: "Ctrl+Shift+N"},
+	)
+}
+
+// actions on a file or folder of the notes list or the sidebar
+func fileContextItems(path string, isFolder bool, open func()) []MenuItem {
+	openLabel := "&Open"
+	if isFolder {
+		openLabel = "&Open Folder"
+	}
+	return []MenuItem{
+		{Label: openLabel, Run: open},
+		{Label: "&Rename...", Run: func() { openRenameDialog(path) }},
+		{Label: "&Delete", Run: func() { confirmTrash(path) }},
+		menuSeparator(),
+		{Label: "Re&veal in File Manager", Run: func() { revealInFileManager(path) }},
+	}
+}
+
+func openRenameDialog(path string) {
+	name := filepath.Base(path)
+	box := newDialogInput("", name)
+	// select the name without the extension
+	box.anchor = 0
+	box.caret = len(name) - len(filepath.Ext(name))
+	pushDialog(&Dialog{
+		Title:   "Rename",
+		Fields:  []*DialogField{{Label: "New name for " + name, Box: box}},
+		Buttons: okCancelButtons("Rename"),
+		Validate: func(d *Dialog) string {
+			switch name := d.Value(0); {
+			case name == "":
+				return "Enter a name"
+			case name == "." || name == "..":
+				return "Enter a name other than " + name
+			case strings.ContainsAny(name, `/\`):
+				// a rename stays in its folder, moving is done elsewhere
+				return "The name cannot contain / or \\"
+			}
+			return ""
+		},
+		OnResult: func(d *Dialog, button string) error {
+			to := filepath.Join(filepath.Dir(path), d.Value(0))
+			if to == path {
+				return nil
+			}
+			if err := renamePath(path, to); err != nil {
+				return err
+			}
+			refreshFileLists(filepath.Base(to))
+			return nil
+		},
+	})
+}
+
+func confirmTrash(path string) {
+	confirmDialog("Delete", "Move "+filepath.Base(path)+" to the trash?", "Delete", true, func() {
+		if err := moveToTrash(path); err != nil {
+			notifyError("Could not delete %s: %v", filepath.Base(path), err)
+		}
+		refreshFileLists("")
+	})
+}
+
+// lists that show files again after a file operation from a context menu
+func refreshFileLists(selectName string) {
+	if ui.ShowFilePicker {
+		pickerReload(selectName)
+	}
+	if ui.ShowNotesPanel {
+		reloadNotesList()
+	}
+	sidebar.lastCheck = time.Time{}
+}
+
+// shows path in the system file manager, on Linux by opening its folder
+func revealInFileManager(path string) {
+	dir := filepath.Dir(path)
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", "-R", path)
+	case "windows":
+		cmd = exec.Command("explorer", "/select,", path)
+	default:
+		cmd = exec.Command("xdg-open", dir)
+	}
+	if err := cmd.Start(); err != nil {
+		notifyError("Could not open the file manager: %v", err)
+		return
+	}
+	go cmd.Wait()
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Modal stack and dialogs. Everything that blocks the editor (dialogs, the command
+// palette, quick open) is pushed on ui.Modals, only the top one gets input, the
+// others are drawn below it. A Dialog is a title, an optional message, input
+// fields and buttons. Enter presses the default button, Esc the cancel button. The
+// default button runs Validate and then OnResult, an error from either is shown in
+// the dialog and keeps it open.
+
+type Modal interface {
+	drawModal(top bool)
+}
+
+type ButtonRole int
+
+const (
+	ButtonNormal ButtonRole = iota
+	ButtonDefault
+	ButtonCancel
+)
+
+type DialogButton struct {
+	Label  string
+	Role   ButtonRole
+	Danger bool // destructive action, drawn in red
+}
+
+type DialogField struct {
+	Label   string
+	Box     *InputBox
+	Choices []string // picks one of these instead of free text, Left/Right or a click changes it
+}
+
+type Dialog struct {
+	Title   string
+	Message string
+	Fields  []*DialogField
+	Buttons []DialogButton
+	Width   int
+
+	// checks the fields before the default button, "" when they are fine
+	Validate func(d *Dialog) string
+	// called with the label of the pressed button, never for the cancel button
+	OnResult func(d *Dialog, button string) error
+
+	Error string
+	focus int
+}
+
+// returned by OnResult to keep the dialog open without showing a message, for
+// example while a confirm on top of it decides
+var errKeepDialog = errors.New("keep dialog open")
+
+// set by pushModal, the new top gets no input in the frame it was opened so the
+// key that opened it does not press its default button
+var modalFresh bool
+
+func pushModal(m Modal) {
+	ui.Modals = append(ui.Modals, m)
+	ui.ActiveMenu = ""
+	modalFresh = true
+}
+
+func closeModal(m Modal) {
+	for i := len(ui.Modals) - 1; i >= 0; i-- {
+		if ui.Modals[i] == m {
+			ui.Modals = append(ui.Modals[:i], ui.Modals[i+1:]...)
+			return
+		}
+	}
+}
+
+func modalOpen() bool {
+	return len(ui.Modals) > 0
+}
+
+// draws the stack bottom to top, modals pushed while drawing show up next frame
+func DrawModal(ui *UIState) {
+	fresh := modalFresh
+	modalFresh = false
+	stack := append([]Modal(nil), ui.Modals...)
+	for i, m := range stack {
+		m.drawModal(i == len(stack)-1 && !fresh)
+	}
+}
+
+// ------------------------------------------------------------------------------------
+
+// text field for a dialog, history is the input history it browses ("" for none)
+func newDialogInput(history, text string) *InputBox {
+	box := &InputBox{MaxChars: 256, History: history}
+	box.SetText(text)
+	box.SelectAll()
+	return box
+}
+
+// field that picks one of choices, value is the first one when it is not among them
+func newDialogChoice(label string, choices []string, value string) *DialogField {
+	f := &DialogField{Label: label, Box: &InputBox{MaxChars: 256}, Choices: choices}
+	if !slices.Contains(choices, value) && len(choices) > 0 {
+		value = choices[0]
+	}
+	f.Box.SetText(value)
+	return f
+}
+
+// moves a choice field to the next (1) or previous (-1) choice
+func (f *DialogField) cycle(dir int) {
+	if len(f.Choices) == 0 {
+		return
+	}
+	i := slices.Index(f.Choices, f.Box.Text)
+	f.Box.SetText(f.Choices[(i+dir+len(f.Choices))%len(f.Choices)])
+}
+
+func pushDialog(d *Dialog) *Dialog {
+	pushModal(d)
+	return d
+}
+
+// Yes / No question, onConfirm runs when the confirm button is pressed
+func confirmDialog(title, message, confirm string, danger bool, onConfirm func()) *Dialog {
+	return pushDialog(&Dialog{
+		Title:   title,
+		Message: message,
+		Buttons: []DialogButton{
+			{Label: "Cancel", Role: ButtonCancel},
+			{Label: confirm, Role: ButtonDefault, Danger: danger},
+		},
+		OnResult: func(d *Dialog, button string) error {
+			onConfirm()
+			return nil
+		},
+	})
+}
+
+// value of field i, "" when there is no such field
+func (d *Dialog) Value(i int) string {
+	if i < 0 || i >= len(d.Fields) {
+		return ""
+	}
+	return strings.TrimSpace(d.Fields[i].Box.Text)
+}
+
+func (d *Dialog) Close() {
+	closeModal(d)
+}
+
+func (d *Dialog) messageLines() []string {
+	if d.Message == "" {
+		return nil
+	}
+	return wrapText(d.Message, (d.width()-40)/CHAR_IMAGE_WIDTH)
+}
+
+func (d *Dialog) width() int {
+	w := d.Width
+	if w <= 0 {
+		w = 480
+	}
+	return max(200, min(w, windowWidth-40))
+}
+
+func (d *Dialog) height() int {
+	h := 44 + 12 + len(d.messageLines())*18
+	h += len(d.Fields) * (18 + 32 + 10)
+	if d.Error != "" {
+		h += 22
+	}
+	return h + 52
+}
+
+// presses a button: cancel closes, the others validate and call OnResult
+func (d *Dialog) press(b DialogButton) {
+	if b.Role == ButtonCancel {
+		d.Close()
+		return
+	}
+	if b.Role == ButtonDefault && d.Validate != nil {
+		if msg := d.Validate(d); msg != "" {
+			d.Error = msg
+			return
+		}
+	}
+	for _, f := range d.Fields {
+		f.Box.AddToHistory()
+	}
+	if d.OnResult != nil {
+		if err := d.OnResult(d, b.Label); err != nil {
+			if err != errKeepDialog {
+				d.Error = err.Error()
+			}
+			return
+		}
+	}
+	d.Close()
+}
+
+func (d *Dialog) buttonWithRole(role ButtonRole) (DialogButton, bool) {
+	for _, b := range d.Buttons {
+		if b.Role == role {
+			return b, true
+		}
+	}
+	return DialogButton{}, false
+}
+
+func (d *Dialog) drawModal(top bool) {
+	w, h := d.width(), d.height()
+	x := (windowWidth - w) / 2
+	y := max(10, (windowHeight-h)/2)
+
+	rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), ModernOverlay)
+	drawShadow(float32(x), float32(y), float32(w), float32(h), 6, 12)
+	rl.DrawRectangle(int32(x), int32(y), int32(w), int32(h), ModernMedium)
+	rl.DrawRectangleLines(int32(x), int32(y), int32(w), int32(h), ModernBorder)
+
+	// header
+	rl.DrawRectangle(int32(x), int32(y), int32(w), 44, ModernDark)
+	DrawText(d.Title, x+20, y+16, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+
+	cy := y + 44 + 12
+	for _, line := range d.messageLines() {
+		DrawText(line, x+20, cy, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+		cy += 18
+	}
+
+	// fields, laid out every frame so they follow the window size
+	if d.focus >= len(d.Fields) {
+		d.focus = 0
+	}
+	for i, f := range d.Fields {
+		DrawText(f.Label, x+20, cy, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		f.Box.Rect = rl.NewRectangle(float32(x+20), float32(cy+18), float32(w-40), 32)
+		f.Box.Focused = i == d.focus
+		cy += 18 + 32 + 10
+	}
+
+	if d.Error != "" {
+		DrawText(clampText(d.Error, (w-40)/CHAR_IMAGE_WIDTH), x+20, cy, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.danger")
+	}
+
+	if top {
+		d.handleInput()
+	}
+	for _, f := range d.Fields {
+		f.Box.Draw()
+		if len(f.Choices) > 0 {
+			r := f.Box.Rect
+			DrawText("< >", int(r.X+r.Width)-3*CHAR_IMAGE_WIDTH-10, int(r.Y)+11, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		}
+	}
+	if !isModalOpen(d) {
+		return
+	}
+
+	// buttons right aligned, the default one last
+	bx := x + w - 20
+	by := y + h - 44
+	for i := len(d.Buttons) - 1; i >= 0; i-- {
+		b := d.Buttons[i]
+		bw := max(80, len(b.Label)*CHAR_IMAGE_WIDTH+24)
+		bx -= bw
+		idle, press := ModernMedium, ModernAccent
+		if b.Role == ButtonDefault {
+			idle = ModernLight
+		}
+		if b.Danger || b.Role == ButtonCancel {
+			press = ModernDanger
+		}
+		if b.Role == ButtonDefault && !b.Danger {
+			press = ModernSuccess
+		}
+		if DrawModernButton(b.Label, int32(bx), int32(by), int32(bw), 32, ModernText, press, ModernLight, idle, true) && top {
+			d.press(b)
+			return
+		}
+		if b.Role == ButtonDefault {
+			rl.DrawRectangleLines(int32(bx), int32(by), int32(bw), 32, ModernAccent)
+		}
+		bx -= 10
+	}
+}
+
+// false once m was closed, for example by one of its own buttons
+func isModalOpen(m Modal) bool {
+	for _, open := range ui.Modals {
+		if open == m {
+			return true
+		}
+	}
+	return false
+}
+
+// keyboard of the top dialog: the focused field, Tab between fields, Enter, Esc
+func (d *Dialog) handleInput() {
+	mouse := rl.GetMousePosition()
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+		for i, f := range d.Fields {
+			if rl.CheckCollisionPointRec(mouse, f.Box.Rect) {
+				d.focus = i
+				f.cycle(1)
+			}
+		}
+	}
+	if len(d.Fields) > 0 {
+		if rl.IsKeyPressed(rl.KeyTab) {
+			if shiftDown() {
+				d.focus = (d.focus + len(d.Fields) - 1) % len(d.Fields)
+			} else {
+				d.focus = (d.focus + 1) % len(d.Fields)
+			}
+		}
+		field := d.Fields[d.focus]
+		box := field.Box
+		before := box.Text
+		switch {
+		case len(field.Choices) == 0:
+			box.HandleInput()
+		case rl.IsKeyPressed(rl.KeyRight) || rl.IsKeyPressed(rl.KeyDown):
+			field.cycle(1)
+		case rl.IsKeyPressed(rl.KeyLeft) || rl.IsKeyPressed(rl.KeyUp):
+			field.cycle(-1)
+		}
+		if box.Text != before {
+			d.Error = ""
+		}
+		for i, f := range d.Fields {
+			f.Box.Focused = i == d.focus
+		}
+	}
+
+	if rl.IsKeyPressed(rl.KeyEscape) {
+		if b, ok := d.buttonWithRole(ButtonCancel); ok {
+			d.press(b)
+		} else {
+			d.Close()
+		}
+		return
+	}
+	if rl.IsKeyPressed(rl.KeyEnter) || rl.IsKeyPressed(rl.KeyKpEnter) {
+		if b, ok := d.buttonWithRole(ButtonDefault); ok {
+			d.press(b)
+		}
+	}
+}
+
+// splits text into lines of at most width characters at spaces
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// cuts text to width characters, marking the cut with "..."
+func clampText(text string, width int) string {
+	if len(text) <= width {
+		return text
+	}
+	if width <= 3 {
+		return text[:max(0, width)]
+	}
+	return text[:width-3] + "..."
+}
@@ -1,22 +1,17 @@
 package main
 
 import (
-	"fmt"
-	"os"
-
 	rl "github.com/gen2brain/raylib-go/raylib"
-	"golang.design/x/clipboard"
 )
 
-var scrollOffsetX int = 0
-var scrollOffsetY int = 0
-
+// text rows that fit in the active pane
 func getVisibleRows() int {
-	return (windowHeight - editorTopPadding - editorBottomPadding) / CHAR_IMAGE_HEIGHT
+	return max(1, (activePane.h-editorYPadding)/CHAR_IMAGE_HEIGHT)
 }
 
+// text columns that fit in the active pane
 func getVisibleCols() int {
-	return (windowWidth - editorXPadding*2) / CHAR_IMAGE_WIDTH
+	return max(1, (activePane.w-editorXPadding*2-gutterWidth()-minimapWidth())/CHAR_IMAGE_WIDTH)
 }
 
 func getRowWidth(row int) int {
@@ -53,18 +48,40 @@ func ensureCursorVisible(cursor *Cursor) {
 	visibleRows := getVisibleRows()
 	visibleCols := getVisibleCols()
 
+	if settings.WordWrap {
+		// scroll by display rows, nothing to scroll horizontally
+		row := cursorVisualRow()
+		if row < activePane.scrollY {
+			activePane.scrollY = row
+		} else if row >= activePane.scrollY+visibleRows {
+			activePane.scrollY = row - visibleRows + 1
+		}
+		maxScrollY := totalDisplayRows() - visibleRows
+		if maxScrollY < 0 {
+			maxScrollY = 0
+		}
+		if activePane.scrollY > maxScrollY {
+			activePane.scrollY = maxScrollY
+		}
+		if activePane.scrollY < 0 {
+			activePane.scrollY = 0
+		}
+		activePane.scrollX = 0
+		return
+	}
+
 	// vertical scrolling
-	if cursor.y < scrollOffsetY {
-		scrollOffsetY = cursor.y
-	} else if cursor.y >= scrollOffsetY+visibleRows {
-		scrollOffsetY = cursor.y - visibleRows + 1
+	if cursor.y < activePane.scrollY {
+		activePane.scrollY = cursor.y
+	} else if cursor.y >= activePane.scrollY+visibleRows {
+		activePane.scrollY = cursor.y - visibleRows + 1
 	}
 
 	// horizontal scrolling
-	if cursor.x < scrollOffsetX {
-		scrollOffsetX = cursor.x
-	} else if cursor.x >= scrollOffsetX+visibleCols {
-		scrollOffsetX = cursor.x - visibleCols + 1
+	if cursor.x < activePane.scrollX {
+		activePane.scrollX = cursor.x
+	} else if cursor.x >= activePane.scrollX+visibleCols {
+		activePane.scrollX = cursor.x - visibleCols + 1
 	}
 
 	// check to not scroll beyond content
@@ -72,168 +89,77 @@ func ensureCursorVisible(cursor *Cursor) {
 	if maxScrollY < 0 {
 		maxScrollY = 0
 	}
-	if scrollOffsetY > maxScrollY {
-		scrollOffsetY = maxScrollY
+	if activePane.scrollY > maxScrollY {
+		activePane.scrollY = maxScrollY
 	}
-	if scrollOffsetY < 0 {
-		scrollOffsetY = 0
+	if activePane.scrollY < 0 {
+		activePane.scrollY = 0
 	}
 
 	maxScrollX := getMaxContentWidth() - visibleCols
 	if maxScrollX < 0 {
 		maxScrollX = 0
 	}
-	if scrollOffsetX > maxScrollX {
-		scrollOffsetX = maxScrollX
+	if activePane.scrollX > maxScrollX {
+		activePane.scrollX = maxScrollX
 	}
-	if scrollOffsetX < 0 {
-		scrollOffsetX = 0
+	if activePane.scrollX < 0 {
+		activePane.scrollX = 0
 	}
 }
 
-func handleEditorInput(cursor *Cursor) {
-	mouseWheel := rl.GetMouseWheelMove()
-	if mouseWheel != 0 {
-		if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
-			// handle horizontal scrolling with Shift+Scroll
-			scrollOffsetX -= int(mouseWheel * 5) // Scroll 5 chars at a time
-			maxScrollX := getMaxContentWidth() - getVisibleCols()
-			if maxScrollX < 0 {
-				maxScrollX = 0
-			}
-			if scrollOffsetX < 0 {
-				scrollOffsetX = 0
-			}
-			if scrollOffsetX > maxScrollX {
-				scrollOffsetX = maxScrollX
-			}
-			// ensureCursorVisible(cursor)
-		} else {
-			// vertical scrolling
-			scrollOffsetY -= int(mouseWheel * 3) // 3 lines at a time
-			maxScrollY := usedRows - getVisibleRows()
-			maxScrollY += 15 // scroll extra 15 lines when available
-			if maxScrollY < 0 {
-				maxScrollY = 0
-			}
-			if scrollOffsetY < 0 {
-				scrollOffsetY = 0
-			}
-			if scrollOffsetY > maxScrollY {
-				scrollOffsetY = maxScrollY
-			}
-			// ensureCursorVisible(cursor)
+// scrolls the active pane, Shift+wheel scrolls sideways
+func scrollWithWheel(mouseWheel float32) {
+	if (rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift)) && !settings.WordWrap {
+		// handle horizontal scrolling with Shift+Scroll
+		activePane.scrollX -= int(mouseWheel * 5) // Scroll 5 chars at a time
+		maxScrollX := getMaxContentWidth() - getVisibleCols()
+		if maxScrollX < 0 {
+			maxScrollX = 0
 		}
-	}
-
-	if rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl) {
-		if rl.IsKeyPressed(rl.KeyS) {
-			if currentFile != "Untitled" {
-				saveTextGridToFile(currentFile)
-				fmt.Println("Saved:", currentFile)
-				editorStatus = "Saved " + currentFile
-			} else {
-				ui.ModalOpen = "SaveAs"
-				ui.InputBoxes = []*InputBox{
-					{
-						Rect:     rl.NewRectangle(150, 150, 300, 30),
-						Text:     "",
-						MaxChars: 64,
-					},
-				}
-			}
+		if activePane.scrollX < 0 {
+			activePane.scrollX = 0
 		}
-	}
-
-	if rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl) {
-		// Exit
-		if rl.IsKeyPressed(rl.KeyQ) {
-
-			os.Exit(1)
+		if activePane.scrollX > maxScrollX {
+			activePane.scrollX = maxScrollX
 		}
-
-		// Copy
-		if rl.IsKeyPressed(rl.KeyC) && selection.Active {
-			editorClipboard = getSelectedText()
-			// rl.SetClipboardText(editorClipboard)
-			clipboard.Write(clipboard.FmtText, []byte(editorClipboard))
-			selection.reset()
+		// ensureCursorVisible(cursor)
+	} else {
+		// vertical scrolling
+		activePane.scrollY -= int(mouseWheel * 3) // 3 lines at a time
+		maxScrollY := totalDisplayRows() - getVisibleRows()
+		maxScrollY += 15 // scroll extra 15 lines when available
+		if maxScrollY < 0 {
+			maxScrollY = 0
 		}
-
-		// Paste
-		if rl.IsKeyPressed(rl.KeyV) && editorClipboard != "" {
-			ensureGridCapacityForPaste(cursor.x, cursor.y, editorClipboard)
-			undoStack = append(undoStack, takeSnapshot())
-			redoStack = nil
-
-			insertStringAtCursor(editorClipboard)
-			selection.Active = false
-			ensureCursorVisible(cursor)
+		if activePane.scrollY < 0 {
+			activePane.scrollY = 0
 		}
-
-		if (rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl)) && rl.IsKeyPressed(rl.KeyA) {
-			selection.Active = true
-			selection.StartX = 0
-			selection.StartY = 0
-			selection.EndY = editorRows - 1
-			selection.EndX = editorCols - 1
+		if activePane.scrollY > maxScrollY {
+			activePane.scrollY = maxScrollY
 		}
+		// ensureCursorVisible(cursor)
+	}
+}
 
+func handleEditorInput(cursor *Cursor) {
+	// the wheel scrolls the pane under the mouse, focused or not
+	mouseWheel := rl.GetMouseWheelMove()
+	if mouseWheel != 0 && !mouseOverSidebar() {
+		withPane(paneAt(int(rl.GetMouseX()), int(rl.GetMouseY())), func() { scrollWithWheel(mouseWheel) })
 	}
-	// selection arrows
-	if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
-		if rl.IsKeyPressed(rl.KeyLeft) || rl.IsKeyPressedRepeat(rl.KeyLeft) {
-			selection.Active = true
-			if !selection.ArrowSelect {
-				selection.StartX = cursor.x
-				selection.StartY = cursor.y
-				selection.ArrowSelect = true
-			}
-			selection.EndX = cursor.x - 1
-			selection.EndY = cursor.y
-			cursor.moveLeft()
-			return
 
-		}
-		if rl.IsKeyPressed(rl.KeyRight) || rl.IsKeyPressedRepeat(rl.KeyRight) {
-			selection.Active = true
-			if !selection.ArrowSelect {
-				selection.StartX = cursor.x
-				selection.StartY = cursor.y
-				selection.ArrowSelect = true
-			}
-			selection.EndX = cursor.x + 1
-			selection.EndY = cursor.y
-			cursor.moveRight()
-			return
-		}
-		if rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp) {
-			selection.Active = true
-			if !selection.ArrowSelect {
-				selection.StartX = cursor.x
-				selection.StartY = cursor.y
-				selection.ArrowSelect = true
-			}
-			cursor.moveUp()
-			selection.EndX = cursor.x
-			selection.EndY = cursor.y
-			return
-		}
-		if rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown) {
-			selection.Active = true
-			if !selection.ArrowSelect {
-				selection.StartX = cursor.x
-				selection.StartY = cursor.y
-				selection.ArrowSelect = true
-			}
-			cursor.moveDown()
-			selection.EndX = cursor.x
-			selection.EndY = cursor.y
-			return
-		}
+	// the open tag completion list takes Up/Down/Enter/Tab/Esc
+	if handleTagCompletionKeys() {
+		return
+	}
 
+	// shortcuts bound to commands (save, copy, paste, view toggles, ...)
+	if dispatchKeybindings() {
+		return
 	}
 
+	typed := false
 	for char := rl.GetCharPressed(); char > 0; char = rl.GetCharPressed() {
 		if char >= 32 && char <= 126 {
 			undoStack = append(undoStack, takeSnapshot())
@@ -245,6 +171,10 @@ func handleEditorInput(cursor *Cursor) {
 			cursor.insert(byte(1))
 		}
 		ensureCursorVisible(cursor)
+		typed = true
+	}
+	if typed {
+		updateTagCompletion()
 	}
 
 	if rl.IsKeyPressed(rl.KeyEnter) {
@@ -257,98 +187,93 @@ func handleEditorInput(cursor *Cursor) {
 		cursor.backspace()
 		selection.reset()
 		ensureCursorVisible(cursor)
+		if tagCompletion.active {
+			updateTagCompletion()
+		}
 	}
+}
 
-	if rl.IsKeyPressed(rl.KeyLeft) || rl.IsKeyPressedRepeat(rl.KeyLeft) {
-		cursor.moveLeft()
-		selection.reset()
-		ensureCursorVisible(cursor)
-		// time.Sleep(33 * time.Millisecond)
-	}
+// ------------------------------------------------------------------------------------
+// cursor movement, bound to the arrows, Home/End, PageUp/PageDown and Tab by the
+// cursor.* and edit.tab commands
 
-	if rl.IsKeyPressed(rl.KeyRight) || rl.IsKeyPressedRepeat(rl.KeyRight) {
-		cursor.moveRight()
-		selection.reset()
-		ensureCursorVisible(cursor)
-		// time.Sleep(33 * time.Millisecond)
-	}
-
-	if rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp) {
-		cursor.moveUp()
-		selection.reset()
-		ensureCursorVisible(cursor)
-		// time.Sleep(33 * time.Millisecond)
-	}
+func moveCursor(move func()) {
+	move()
+	selection.reset()
+	ensureCursorVisible(cursor)
+}
 
-	if rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown) {
-		cursor.moveDown()
-		selection.reset()
-		ensureCursorVisible(cursor)
-		// time.Sleep(33 * time.Millisecond)
-	}
+// Shift+arrows, the selection goes from where the arrow selection started to the cursor
+func selectWithCursor(move func(), endDX int) {
+	selection.Active = true
+	if !selection.ArrowSelect {
+		selection.StartX = cursor.x
+		selection.StartY = cursor.y
+		selection.ArrowSelect = true
+	}
+	if endDX != 0 {
+		// left and right select the character they move over
+		selection.EndX = cursor.x + endDX
+		selection.EndY = cursor.y
+		move()
+	} else {
+		move()
+		selection.EndX = cursor.x
+		selection.EndY = cursor.y
+	}
+	ensureCursorVisible(cursor)
+}
 
-	if rl.IsKeyPressed(rl.KeyTab) {
-		for i := 0; i < 4; i++ {
-			cursor.insert(' ')
+func cursorLeft()        { moveCursor(cursor.moveLeft) }
+func cursorRight()       { moveCursor(cursor.moveRight) }
+func cursorUp()          { moveCursor(cursor.moveUp) }
+func cursorDown()        { moveCursor(cursor.moveDown) }
+func cursorSelectLeft()  { selectWithCursor(cursor.moveLeft, -1) }
+func cursorSelectRight() { selectWithCursor(cursor.moveRight, 1) }
+func cursorSelectUp()    { selectWithCursor(cursor.moveUp, 0) }
+func cursorSelectDown()  { selectWithCursor(cursor.moveDown, 0) }
+
+// Page Up/Down for faster scrolling
+func cursorPage(dir int) {
+	if settings.WordWrap {
+		for i := 0; i < getVisibleRows(); i++ {
+			cursor.moveVisual(dir)
 		}
-		ensureCursorVisible(cursor)
-	}
-
-	if rl.IsKeyDown(rl.KeyLeftControl) && rl.IsKeyPressed(rl.KeyZ) {
-		undo()
-	}
-	if rl.IsKeyDown(rl.KeyLeftControl) && rl.IsKeyDown(rl.KeyLeftShift) && rl.IsKeyPressed(rl.KeyZ) {
-		redo()
+	} else {
+		cursor.y = max(0, min(cursor.y+dir*getVisibleRows(), usedRows-1))
+		cursor.clampXToLineEnd()
 	}
+	ensureCursorVisible(cursor)
+}
 
-	// ----- gen`1`
+func cursorPageUp()   { cursorPage(-1) }
+func cursorPageDown() { cursorPage(1) }
 
-	// Page Up/Down for faster scrolling
-	if rl.IsKeyPressed(rl.KeyPageUp) {
-		visibleRows := getVisibleRows()
-		cursor.y -= visibleRows
-		if cursor.y < 0 {
-			cursor.y = 0
-		}
-		cursor.clampXToLineEnd()
-		ensureCursorVisible(cursor)
+// Home: beginning of the line, or of the wrapped row
+func cursorLineStart() {
+	if settings.WordWrap {
+		cursor.moveToRowStart()
+	} else {
+		cursor.x = 0
 	}
+	ensureCursorVisible(cursor)
+}
 
-	if rl.IsKeyPressed(rl.KeyPageDown) {
-		visibleRows := getVisibleRows()
-		cursor.y += visibleRows
-		if cursor.y >= usedRows {
-			cursor.y = usedRows - 1
-		}
+// End: end of the line, or of the wrapped row
+func cursorLineEnd() {
+	if settings.WordWrap {
+		cursor.moveToRowEnd()
+	} else {
 		cursor.clampXToLineEnd()
-		ensureCursorVisible(cursor)
-	}
-
-	// Home/End keys
-	if rl.IsKeyPressed(rl.KeyHome) {
-		if rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl) {
-			// Ctrl+Home: Go to beginning of document
-			cursor.reset()
-		} else {
-			// Home: Go to beginning of line
-			cursor.x = 0
-		}
-		ensureCursorVisible(cursor)
 	}
+	ensureCursorVisible(cursor)
+}
 
-	if rl.IsKeyPressed(rl.KeyEnd) {
-		if rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl) {
-			// Ctrl+End: Go to end of document
-			cursor.y = usedRows - 1
-			cursor.clampXToLineEnd()
-		} else {
-			// End: Go to end of line
-			cursor.clampXToLineEnd()
-		}
-		ensureCursorVisible(cursor)
+func insertTab() {
+	for i := 0; i < 4; i++ {
+		cursor.insert(' ')
 	}
-
-	// ----- gen`1`
+	ensureCursorVisible(cursor)
 }
 
 func getSelectedText() string {
@@ -386,53 +311,5 @@ func getSelectedText() string {
 		}
 
 	}
-	fmt.Println("getSelectedText: ", result)
 	return result
 }
-
-func drawScrollIndicators() {
-	visibleRows := getVisibleRows()
-	visibleCols := getVisibleCols()
-	maxContentWidth := getMaxContentWidth()
-
-	// vertical scrollbar
-	if usedRows > visibleRows {
-		scrollBarX := int32(windowWidth - 10)
-		scrollBarY := int32(editorTopPadding)
-		scrollBarH := int32(windowHeight - editorTopPadding - editorBottomPadding)
-
-		rl.DrawRectangle(scrollBarX, scrollBarY, 8, scrollBarH, rl.DarkGray)
-
-		thumbHeight := int32(float32(scrollBarH) * float32(visibleRows) / float32(usedRows))
-		if thumbHeight < 10 {
-			thumbHeight = 10
-		}
-
-		maxScrollY := usedRows - visibleRows
-		if maxScrollY > 0 {
-			thumbY := scrollBarY + int32(float32(scrollBarH-thumbHeight)*float32(scrollOffsetY)/float32(maxScrollY))
-
-			rl.DrawRectangle(scrollBarX+1, thumbY, 6, thumbHeight, rl.Gray)
-		}
-	}
-
-	// horizontal scroll bar
-	if maxContentWidth > visibleCols {
-		scrollBarX := int32(editorXPadding)
-		scrollBarY := int32(windowHeight - (editorBottomPadding * 2) + 10)
-		scrollBarW := int32(windowWidth - editorXPadding*2 - 15)
-
-		rl.DrawRectangle(scrollBarX, scrollBarY, scrollBarW, 6, rl.DarkGray)
-
-		thumbWidth := int32(float32(scrollBarW) * float32(visibleCols) / float32(maxContentWidth))
-		if thumbWidth < 10 {
-			thumbWidth = 10
-		}
-
-		maxScrollX := maxContentWidth - visibleCols
-		if maxScrollX > 0 {
-			thumbX := scrollBarX + int32(float32(scrollBarW-thumbWidth)*float32(scrollOffsetX)/float32(maxScrollX))
-			rl.DrawRectangle(thumbX, scrollBarY+1, thumbWidth, 4, rl.Gray)
-		}
-	}
-}
@@ -4,12 +4,13 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
-	"slices"
 	"strings"
+	"time"
+
+	"golang.design/x/clipboard"
 )
 
 const editorXPadding int = 5
@@ -20,8 +21,6 @@ const editorBottomPadding int = 20
 var windowHeight int = 460
 var windowWidth int = 640
 
-var visibleCols int = windowWidth / CHAR_IMAGE_WIDTH
-var visibleRows int = windowHeight / CHAR_IMAGE_HEIGHT
 var editorCols int = windowWidth / CHAR_IMAGE_WIDTH   // ^ ^ ^
 var editorRows int = windowHeight / CHAR_IMAGE_HEIGHT // >
 var usedRows int = 1
@@ -29,7 +28,8 @@ var usedRows int = 1
 var currentFile string = "Untitled"
 var textGrid [][]byte = getTextGrid(editorRows, editorCols)
 
-var cursor = &Cursor{}
+// cursor and selection of the active pane
+var cursor = &activePane.cursor
 var ui = &UIState{
 	CurrentView: "editor",
 }
@@ -37,6 +37,27 @@ var ui = &UIState{
 var editorStatus string = ""
 var editorClipboard string
 
+// false when the system clipboard could not be opened, copies then stay in the editor
+var clipboardReady bool
+
+func initClipboard() {
+	if err := clipboard.Init(); err != nil {
+		notifyWarning("System clipboard unavailable, copies stay inside the editor: %v", err)
+		return
+	}
+	clipboardReady = true
+}
+
+// copies text to the system clipboard, editorClipboard is set by the caller
+func writeClipboard(text string) {
+	if !clipboardReady {
+		return
+	}
+	if clipboard.Write(clipboard.FmtText, []byte(text)) == nil {
+		notifyError("Could not write to the system clipboard")
+	}
+}
+
 // ------------------------------------------------------------------------------------
 
 type EditorSnapshot struct {
@@ -80,6 +101,7 @@ func restoreSnapshot(s EditorSnapshot) {
 	cursor.x = s.CursorX
 	cursor.y = s.CursorY
 	usedRows = s.UsedRows
+	markBufferChanged(0)
 	editorRows = s.NumRows
 	editorCols = s.NumCols
 
@@ -152,6 +174,8 @@ func resetUndoRedoStacks() {
 type FileEntry struct {
 	Name     string
 	IsFolder bool
+	Size     int64
+	ModTime  time.Time
 }
 
 func listAllFiles(dir string) []FileEntry {
@@ -169,26 +193,29 @@ func listAllFiles(dir string) []FileEntry {
 	// add folders first
 	for _, f := range files {
 		if f.IsDir() {
-			entries = append(entries, FileEntry{
-				Name:     f.Name(),
-				IsFolder: true,
-			})
+			entries = append(entries, newFileEntry(f))
 		}
 	}
 
 	// files last
 	for _, f := range files {
 		if !f.IsDir() {
-			entries = append(entries, FileEntry{
-				Name:     f.Name(),
-				IsFolder: false,
-			})
+			entries = append(entries, newFileEntry(f))
 		}
 	}
 
 	return entries
 }
 
+func newFileEntry(f os.DirEntry) FileEntry {
+	entry := FileEntry{Name: f.Name(), IsFolder: f.IsDir()}
+	if info, err := f.Info(); err == nil {
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime()
+	}
+	return entry
+}
+
 func ensureGridCapacityForPaste(startX, startY int, content string) {
 	lines := strings.Split(content, "\n")
 	requiredRows := startY + len(lines)
@@ -244,27 +271,37 @@ func insertStringAt(x, y int, s string) {
 	usedRows = max(usedRows, cursor.y+1)
 }
 
-func listNoteFiles(dir string, foldersOnly bool) []string {
-	// Old --- to refactor
-	os.MkdirAll("/home/void/notes", os.ModePerm)
+// folders ("name/") and notes of a folder below the notes root, ordered by the
+// note pattern
+func listNoteFiles(dir string) []string {
+	root := notesRoot()
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		notifyError("Could not create notes folder %s: %v", root, err)
+		return nil
+	}
 
-	files, err := os.ReadDir("/home/void/" + dir)
+	files, err := os.ReadDir(filepath.Join(root, dir))
 	if err != nil {
-		fmt.Println("error reading dir listnotefiles")
+		notifyError("Could not read notes folder %s: %v", filepath.Join(root, dir), err)
 		return nil
 	}
-	var entries []string
+	var folders, notes []string
 	for _, f := range files {
-		if foldersOnly && f.IsDir() {
-			entries = append(entries, f.Name()+"/")
-		} else if !foldersOnly && !f.IsDir() && filepath.Ext(f.Name()) == ".txt" {
-			entries = append(entries, f.Name())
+		if f.IsDir() && !strings.HasPrefix(f.Name(), ".") {
+			folders = append(folders, f.Name()+"/")
+		} else if !f.IsDir() && isNoteFile(f.Name()) {
+			notes = append(notes, f.Name())
 		}
 	}
 
-	slices.Reverse(entries)
-	fmt.Println(entries)
-	return entries
+	parts := strings.Split(notePattern(), "/")
+	depth := 0
+	if dir = strings.Trim(filepath.ToSlash(dir), "/"); dir != "" {
+		depth = strings.Count(dir, "/") + 1
+	}
+	sortByPatternDate(folders, parts[min(depth, len(parts)-1)])
+	sortByPatternDate(notes, parts[len(parts)-1])
+	return append(folders, notes...)
 }
 
 func clampName(in string, width int32, charWidth int) string {
@@ -290,43 +327,34 @@ func clampName(in string, width int32, charWidth int) string {
 
 	return in
 }
-func deleteFile(path string) {
+func deleteFile(path string) error {
 	fileName := path
 	if _, err := os.Stat(fileName); err == nil {
-		fmt.Println("Attempting to delete " + fileName)
-		copyFile(fileName, fileName+"_backup")
+		if err := copyFile(fileName, fileName+"_backup"); err != nil {
+			return fmt.Errorf("backup failed, not deleting: %w", err)
+		}
 		err = os.Remove(fileName)
 		if err != nil {
-			fmt.Println("Failed to delete file!")
-			return
+			return err
 		}
-		fmt.Println("Deleted file: " + fileName)
-	}
-}
-
-func copyFile(src string, dst string) {
-	data, err := os.ReadFile(src)
-	if err != nil {
-		log.Fatal(err)
-	}
-	err = os.WriteFile(dst, data, 0644)
-	if err != nil {
-		log.Fatal(err)
+		notifyInfo("Deleted %s, a backup was kept as %s", filepath.Base(fileName), filepath.Base(fileName)+"_backup")
 	}
+	return nil
 }
 
 func clearTextGrid() {
+	detachSharedBuffer()
 	var editorCols_ int = windowWidth / CHAR_IMAGE_WIDTH   // ^ ^ ^
 	var editorRows_ int = windowHeight / CHAR_IMAGE_HEIGHT // >
 	fmt.Println("Resizing textGrid to Cols:", editorCols_, "Rows:", editorRows_)
 	textGrid = getTextGrid(editorRows_, editorCols_)
 	runtime.GC()
-	visibleRows = editorRows_
-	visibleCols = editorCols_
 	editorCols = editorCols_
 	editorRows = editorRows_
 	usedRows = 1
 	cursor.reset()
+	markBufferChanged(0)
+	markBufferSaved()
 	editorStatus = "New Buffer Created"
 	currentFile = "Untitled"
 }
@@ -337,6 +365,7 @@ func loadFileIntoTextGrid(path string) (int, error) {
 		return -1, err
 	}
 	defer file.Close()
+	rememberNotePosition()
 
 	clearTextGrid()
 	cursor.reset()
@@ -386,6 +415,9 @@ func loadFileIntoTextGrid(path string) (int, error) {
 	cursor.y = 0
 	usedRows = y + 1
 	currentFile = path
+	markBufferChanged(0)
+	markBufferSaved()
+	noteOpened(path)
 	fmt.Println("Loaded file: ", path)
 	return count, nil
 }
@@ -430,7 +462,12 @@ func saveTextGridToFile(path string) error {
 
 	}
 
-	return writer.Flush()
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	markBufferSaved()
+	noteSaved(path)
+	return nil
 }
 
 // ------------------------------------------------------------------------------------
@@ -453,7 +490,25 @@ func (s *Selection) reset() {
 	s.ArrowSelect = false
 }
 
-var selection Selection
+// keeps the selection inside the buffer after it got shorter, an end past the
+// last line moves to the end of the last line
+func (s *Selection) clamp() {
+	if !s.Active {
+		return
+	}
+	s.StartX, s.StartY = clampPosition(s.StartX, s.StartY)
+	s.EndX, s.EndY = clampPosition(s.EndX, s.EndY)
+}
+
+func clampPosition(x, y int) (int, int) {
+	if y >= usedRows {
+		y = usedRows - 1
+		x = editorCols
+	}
+	return min(x, lineEndX(y)), y
+}
+
+var selection = &activePane.selection
 
 type Cursor struct {
 	x int // Cols
@@ -469,38 +524,6 @@ func (c *Cursor) String() string {
 	return fmt.Sprintf("Cursor[%d, %d]", c.x, c.y)
 }
 
-func (c *Cursor) MoveToClick(x, y int) {
-	// if cell has character, just move there
-	if textGrid[y][x] != 0 {
-		c.x = x
-		c.y = y
-		return
-	}
-
-	// look left in the same line
-	for i := x - 1; i >= 0; i-- {
-		if textGrid[y][i] != 0 {
-			c.x = i
-			c.y = y
-			return
-		}
-	}
-
-	// look upward
-	for j := y - 1; j >= 0; j-- {
-		for i := editorCols - 1; i >= 0; i-- {
-			if textGrid[j][i] != 0 {
-				c.x = i
-				c.y = j
-				return
-			}
-		}
-	}
-
-	// fallback, no idea if this can be reached :)
-	c.reset()
-}
-
 func (c *Cursor) enter() {
 	if c.y+1 >= editorRows {
 		growTextGrid()
@@ -508,6 +531,7 @@ func (c *Cursor) enter() {
 	if usedRows >= editorRows {
 		growTextGrid()
 	}
+	markBufferChanged(c.y)
 
 	// shift lines below down by 1
 	for i := usedRows; i > c.y+1; i-- {
@@ -555,24 +579,7 @@ func (c *Cursor) backspace() {
 		for c.y > startY || (c.y == startY && c.x > startX) {
 			c.backspaceSingle()
 		}
-		// recalculate usedRows after deletion
-		usedRows = 0
-		for y := editorRows - 1; y >= 0; y-- {
-			nonEmpty := false
-			for x := 0; x < editorCols; x++ {
-				if textGrid[y][x] != 0 {
-					nonEmpty = true
-					break
-				}
-			}
-			if nonEmpty {
-				usedRows = y + 1
-				break
-			}
-		}
-		if usedRows == 0 {
-			usedRows = 1
-		}
+		recountUsedRows()
 		// TODO: Maybe recalculate columns aswell?
 
 		selection.Active = false
@@ -586,7 +593,30 @@ func (c *Cursor) backspace() {
 	ensureCursorVisible(c)
 }
 
+// recalculates usedRows after a deletion
+func recountUsedRows() {
+	usedRows = 0
+	for y := editorRows - 1; y >= 0; y-- {
+		nonEmpty := false
+		for x := 0; x < editorCols; x++ {
+			if textGrid[y][x] != 0 {
+				nonEmpty = true
+				break
+			}
+		}
+		if nonEmpty {
+			usedRows = y + 1
+			break
+		}
+	}
+	if usedRows == 0 {
+		usedRows = 1
+	}
+}
+
 func (c *Cursor) backspaceSingle() {
+	markBufferChanged(c.y - 1)
+
 	// if in line or end
 	if c.x > 0 {
 		c.x--
@@ -707,6 +737,10 @@ func (c *Cursor) moveRight() {
 }
 
 func (c *Cursor) moveUp() {
+	if settings.WordWrap {
+		c.moveVisual(-1)
+		return
+	}
 	if c.y > 0 {
 		c.y--
 		if c.x > editorCols {
@@ -727,6 +761,10 @@ func (c *Cursor) moveUp() {
 }
 
 func (c *Cursor) moveDown() {
+	if settings.WordWrap {
+		c.moveVisual(1)
+		return
+	}
 	if c.y < usedRows-1 {
 		c.y++
 		if c.x > editorCols {
@@ -761,6 +799,7 @@ func (c *Cursor) clampXToLineEnd() {
 
 func (c *Cursor) insert(char byte) {
 	c.checkBounds()
+	markBufferChanged(c.y)
 
 	// shift characters right from the end to cursor.x
 	for i := editorCols - 1; i > c.x; i-- {
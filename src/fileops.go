@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// file management used by the file picker. Every function returns its error so
+// the caller can show it, nothing here exits or only logs.
+
+func createFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func createFolder(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", filepath.Base(path))
+	}
+	return os.MkdirAll(path, 0755)
+}
+
+// renames or moves a file / folder, never overwriting an existing one
+func renamePath(from, to string) error {
+	if _, err := os.Stat(to); err == nil {
+		return fmt.Errorf("%s already exists", to)
+	}
+	if err := os.Rename(from, to); err != nil {
+		// only a different file system is copied and removed, any other error
+		// (no permission, busy, a folder into itself) stays an error
+		if !errors.Is(err, syscall.EXDEV) {
+			return err
+		}
+		if err := copyPath(from, to); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(from); err != nil {
+			return err
+		}
+	}
+	renameOpenBuffers(from, to)
+	return nil
+}
+
+// moves a file / folder into another folder
+func movePath(from, toFolder string) (string, error) {
+	info, err := os.Stat(toFolder)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a folder", toFolder)
+	}
+	absFrom, _ := filepath.Abs(from)
+	absTo, _ := filepath.Abs(toFolder)
+	if pathWithin(absTo, absFrom) {
+		return "", fmt.Errorf("cannot move %s into itself", filepath.Base(from))
+	}
+	to := filepath.Join(toFolder, filepath.Base(from))
+	return to, renamePath(from, to)
+}
+
+// copies next to the original as "name copy.ext", "name copy 2.ext", ...
+func duplicatePath(path string) (string, error) {
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		ext = ""
+	}
+	name := strings.TrimSuffix(base, ext)
+
+	for i := 1; i < 1000; i++ {
+		suffix := " copy"
+		if i > 1 {
+			suffix = fmt.Sprintf(" copy %d", i)
+		}
+		to := filepath.Join(dir, name+suffix+ext)
+		if _, err := os.Stat(to); os.IsNotExist(err) {
+			return to, copyPath(path, to)
+		}
+	}
+	return "", fmt.Errorf("too many copies of %s", base)
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copies a file or a whole folder
+func copyPath(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return copyFile(src, dst)
+	}
+	if strings.HasPrefix(dst, src+string(filepath.Separator)) {
+		return fmt.Errorf("cannot copy %s into itself", filepath.Base(src))
+	}
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		return copyFile(p, target)
+	})
+}
+
+// freedesktop.org trash folder, $XDG_DATA_HOME/Trash or ~/.local/share/Trash
+func trashDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "Trash"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// moves a file or folder to the trash, with a .trashinfo so file managers can restore it
+func moveToTrash(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	trash, err := trashDir()
+	if err != nil {
+		return err
+	}
+	filesDir := filepath.Join(trash, "files")
+	infoDir := filepath.Join(trash, "info")
+	if err := os.MkdirAll(filesDir, 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(infoDir, 0700); err != nil {
+		return err
+	}
+
+	// unique name inside the trash
+	name := filepath.Base(abs)
+	for i := 2; ; i++ {
+		_, errFile := os.Stat(filepath.Join(filesDir, name))
+		_, errInfo := os.Stat(filepath.Join(infoDir, name+".trashinfo"))
+		if os.IsNotExist(errFile) && os.IsNotExist(errInfo) {
+			break
+		}
+		name = fmt.Sprintf("%s.%d", filepath.Base(abs), i)
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: abs}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	infoPath := filepath.Join(infoDir, name+".trashinfo")
+	if err := os.WriteFile(infoPath, []byte(info), 0600); err != nil {
+		return err
+	}
+
+	target := filepath.Join(filesDir, name)
+	if err := os.Rename(abs, target); err != nil {
+		// trash on another file system
+		if !errors.Is(err, syscall.EXDEV) {
+			os.Remove(infoPath)
+			return err
+		}
+		if err := copyPath(abs, target); err != nil {
+			os.Remove(infoPath)
+			return err
+		}
+		if err := os.RemoveAll(abs); err != nil {
+			return err
+		}
+	}
+	closeDeletedBuffers(abs)
+	return nil
+}
+
+// true if path is p or inside the folder p
+func pathWithin(path, p string) bool {
+	return path == p || strings.HasPrefix(path, p+string(filepath.Separator))
+}
+
+// points the open buffers to the new location when they (or their folder) were renamed
+func renameOpenBuffers(from, to string) {
+	forEachBuffer(func() { renameOpenBuffer(from, to) })
+}
+
+func renameOpenBuffer(from, to string) {
+	if currentFile == "Untitled" {
+		return
+	}
+	current, err1 := filepath.Abs(currentFile)
+	oldAbs, err2 := filepath.Abs(from)
+	newAbs, err3 := filepath.Abs(to)
+	if err1 != nil || err2 != nil || err3 != nil || !pathWithin(current, oldAbs) {
+		return
+	}
+	currentFile = newAbs + strings.TrimPrefix(current, oldAbs)
+	editorStatus = "Buffer now at " + currentFile
+}
+
+// the text of a deleted file stays open as an unsaved buffer
+func closeDeletedBuffers(deleted string) {
+	forEachBuffer(func() { closeDeletedBuffer(deleted) })
+}
+
+func closeDeletedBuffer(deleted string) {
+	if currentFile == "Untitled" {
+		return
+	}
+	current, err := filepath.Abs(currentFile)
+	if err != nil || !pathWithin(current, deleted) {
+		return
+	}
+	currentFile = "Untitled"
+	markBufferChanged(0)
+	editorStatus = "The open file was deleted, its text is kept as Untitled"
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// File picker panel: a folder listing with name / size / modified columns.
+// Up/Down select, Enter or double-click opens, Backspace (with an empty filter)
+// goes up a folder, typing filters, Ctrl+H toggles hidden files, Esc closes.
+// Clicking a column header sorts by it, clicking it again reverses the order.
+//
+// File operations: Ctrl+N new file, Ctrl+Shift+N new folder, F2 rename,
+// Ctrl+D duplicate, Ctrl+M move, Delete moves to the trash after a confirmation.
+
+const pickerRowHeight = 24
+const pickerDateFormat = "2006-01-02 15:04"
+
+var pickerLastClick struct {
+	index int
+	time  float64
+}
+
+func openFilePicker() {
+	ui.ShowFilePicker = true
+	ui.CurrentPath, _ = filepath.Abs(".")
+	ui.PickerFilter = &InputBox{Rect: rl.NewRectangle(0, 0, 0, 28), MaxChars: 128}
+	if ui.PickerSort == "" {
+		ui.PickerSort = "name"
+	}
+	pickerChangeDir(ui.CurrentPath)
+}
+
+func closeFilePicker() {
+	ui.ShowFilePicker = false
+	ui.PickerFilter = nil
+	pickerCancelAction()
+}
+
+func pickerChangeDir(dir string) {
+	ui.CurrentPath = dir
+	ui.FileEntries = listAllFiles(dir)
+	ui.PickerSelected = 0
+	ui.PickerScroll = 0
+	ui.PickerError = ""
+	if ui.PickerFilter != nil {
+		ui.PickerFilter.SetText("")
+	}
+}
+
+// re-reads the folder and selects the entry with the given name if it is there
+func pickerReload(selectName string) {
+	ui.FileEntries = listAllFiles(ui.CurrentPath)
+	for i, e := range pickerVisibleEntries() {
+		if e.Name == selectName {
+			ui.PickerSelected = i
+			return
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------
+// file operations
+
+var pickerActionLabels = map[string]string{
+	"newFile":   "New file name:",
+	"newFolder": "New folder name:",
+	"rename":    "Rename to:",
+	"move":      "Move to folder:",
+	"delete":    "Move to the trash?",
+}
+
+// starts an operation on the selected entry, prompting for a name where needed
+func pickerStartAction(action string) {
+	entries := pickerVisibleEntries()
+	target := ""
+	if ui.PickerSelected < len(entries) {
+		target = entries[ui.PickerSelected].Name
+	}
+	if target == "" && action != "newFile" && action != "newFolder" {
+		return
+	}
+	ui.PickerError = ""
+	ui.PickerTarget = target
+
+	if action == "duplicate" {
+		to, err := duplicatePath(filepath.Join(ui.CurrentPath, target))
+		pickerActionDone(filepath.Base(to), err)
+		return
+	}
+
+	ui.PickerAction = action
+	if action == "delete" {
+		return
+	}
+	ui.PickerPrompt = &InputBox{Rect: rl.NewRectangle(0, 0, 0, 26), MaxChars: 256}
+	switch action {
+	case "rename":
+		ui.PickerPrompt.SetText(target)
+		// select the name without the extension
+		ui.PickerPrompt.anchor = 0
+		ui.PickerPrompt.caret = len(target) - len(filepath.Ext(target))
+	case "move":
+		ui.PickerPrompt.SetText(ui.CurrentPath + string(filepath.Separator))
+		ui.PickerPrompt.History = "pickerMove"
+	}
+}
+
+func pickerCancelAction() {
+	ui.PickerAction = ""
+	ui.PickerTarget = ""
+	ui.PickerPrompt = nil
+}
+
+// runs the pending operation with the text of the prompt
+func pickerConfirmAction() {
+	name := ""
+	if ui.PickerPrompt != nil {
+		name = strings.TrimSpace(ui.PickerPrompt.Text)
+		ui.PickerPrompt.AddToHistory()
+	}
+	if name == "" && ui.PickerAction != "delete" {
+		ui.PickerError = "A name is required"
+		return
+	}
+	target := filepath.Join(ui.CurrentPath, ui.PickerTarget)
+	// relative names are relative to the folder shown
+	dest := name
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(ui.CurrentPath, name)
+	}
+
+	var err error
+	selectName := filepath.Base(dest)
+	switch ui.PickerAction {
+	case "newFile":
+		err = createFile(dest)
+	case "newFolder":
+		err = createFolder(dest)
+	case "rename":
+		err = renamePath(target, dest)
+	case "move":
+		_, err = movePath(target, dest)
+		selectName = ""
+	case "delete":
+		err = moveToTrash(target)
+		selectName = ""
+	}
+	pickerCancelAction()
+	pickerActionDone(selectName, err)
+}
+
+func pickerActionDone(selectName string, err error) {
+	if err != nil {
+		ui.PickerError = err.Error()
+		notifyError("%v", err)
+		pickerReload("")
+		return
+	}
+	ui.PickerError = ""
+	pickerReload(selectName)
+}
+
+// keys while an operation waits for input
+func handlePickerActionInput() {
+	switch {
+	case rl.IsKeyPressed(rl.KeyEscape):
+		pickerCancelAction()
+	case rl.IsKeyPressed(rl.KeyEnter):
+		pickerConfirmAction()
+	case ui.PickerPrompt != nil:
+		ui.PickerPrompt.HandleInput()
+	}
+}
+
+// prompt / confirmation bar, drawn over the operations toolbar
+func drawPickerActionBar(x, y, w int32) {
+	DrawText(pickerActionLabels[ui.PickerAction], int(x), int(y)+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+	labelW := int32(20 * CHAR_IMAGE_WIDTH)
+	buttonsX := x + w - 180
+
+	confirm := "OK"
+	if ui.PickerAction == "delete" {
+		DrawText(ui.PickerTarget, int(x+labelW), int(y)+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.accent")
+		confirm = "Delete"
+	} else if ui.PickerPrompt != nil {
+		ui.PickerPrompt.Rect = rl.NewRectangle(float32(x+labelW), float32(y), float32(buttonsX-x-labelW-8), 26)
+		ui.PickerPrompt.Draw()
+	}
+
+	confirmColor := ModernSuccess
+	if ui.PickerAction == "delete" {
+		confirmColor = ModernDanger
+	}
+	if DrawModernButton(confirm, buttonsX, y+1, 80, 24, ModernText, confirmColor, ModernLight, ModernDark, true) {
+		pickerConfirmAction()
+		return
+	}
+	if DrawModernButton("Cancel", buttonsX+90, y+1, 80, 24, ModernText, ModernAccent, ModernLight, ModernDark, true) {
+		pickerCancelAction()
+	}
+}
+
+func drawPickerToolbar(x, y int32) {
+	for _, b := range []struct {
+		label, action string
+		w             int32
+	}{
+		{"New File", "newFile", 90},
+		{"New Folder", "newFolder", 108},
+		{"Rename", "rename", 72},
+		{"Duplicate", "duplicate", 99},
+		{"Move", "move", 54},
+		{"Delete", "delete", 72},
+	} {
+		color := ModernAccent
+		if b.action == "delete" {
+			color = ModernDanger
+		}
+		if DrawModernButton(b.label, x, y, b.w, 24, ModernText, color, ModernLight, ModernDark, true) {
+			pickerStartAction(b.action)
+			return
+		}
+		x += b.w + 6
+	}
+}
+
+func pickerGoUp() {
+	parent := filepath.Dir(ui.CurrentPath)
+	if parent == ui.CurrentPath {
+		return
+	}
+	from := filepath.Base(ui.CurrentPath)
+	pickerChangeDir(parent)
+	// keep the folder we came from selected
+	for i, e := range pickerVisibleEntries() {
+		if e.Name == from {
+			ui.PickerSelected = i
+		}
+	}
+}
+
+// entries after the hidden / filter settings, folders first, then the chosen sort
+func pickerVisibleEntries() []FileEntry {
+	filter := ""
+	if ui.PickerFilter != nil {
+		filter = ui.PickerFilter.Text
+	}
+
+	var entries []FileEntry
+	for _, e := range ui.FileEntries {
+		if !ui.PickerShowHidden && strings.HasPrefix(e.Name, ".") {
+			continue
+		}
+		if _, _, ok := fuzzyScore(filter, e.Name); !ok {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.IsFolder != b.IsFolder {
+			return a.IsFolder
+		}
+		c := 0
+		switch ui.PickerSort {
+		case "size":
+			c = cmp.Compare(a.Size, b.Size)
+		case "mtime":
+			c = a.ModTime.Compare(b.ModTime)
+		}
+		if c == 0 {
+			c = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+		if ui.PickerSortDesc {
+			c = -c
+		}
+		return c < 0
+	})
+	return entries
+}
+
+func pickerSetSort(column string) {
+	if ui.PickerSort == column {
+		ui.PickerSortDesc = !ui.PickerSortDesc
+	} else {
+		ui.PickerSort = column
+		ui.PickerSortDesc = column != "name" // newest / biggest first
+	}
+}
+
+// enters a folder or opens a file
+func pickerActivate(entry FileEntry) {
+	fullPath := filepath.Join(ui.CurrentPath, entry.Name)
+	if entry.IsFolder {
+		pickerChangeDir(fullPath)
+		return
+	}
+	closeFilePicker()
+	openFileInEditor(fullPath)
+}
+
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"K", "M", "G", "T"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f P", value)
+}
+
+// keys for the picker, called instead of the editor input while it is open
+func handleFilePickerInput(ui *UIState) {
+	if ui.PickerFilter == nil {
+		return
+	}
+	if ui.PickerAction != "" {
+		handlePickerActionInput()
+		return
+	}
+	entries := pickerVisibleEntries()
+
+	switch {
+	case rl.IsKeyPressed(rl.KeyF2):
+		pickerStartAction("rename")
+		return
+	case rl.IsKeyPressed(rl.KeyDelete):
+		pickerStartAction("delete")
+		return
+	case ctrlDown() && shiftDown() && rl.IsKeyPressed(rl.KeyN):
+		pickerStartAction("newFolder")
+		return
+	case ctrlDown() && rl.IsKeyPressed(rl.KeyN):
+		pickerStartAction("newFile")
+		return
+	case ctrlDown() && rl.IsKeyPressed(rl.KeyD):
+		pickerStartAction("duplicate")
+		return
+	case ctrlDown() && rl.IsKeyPressed(rl.KeyM):
+		pickerStartAction("move")
+		return
+	case rl.IsKeyPressed(rl.KeyEscape):
+		closeFilePicker()
+		return
+	case rl.IsKeyPressed(rl.KeyBackspace) && ui.PickerFilter.Text == "":
+		pickerGoUp()
+		return
+	case rl.IsKeyPressed(rl.KeyEnter) && ui.PickerSelected < len(entries):
+		pickerActivate(entries[ui.PickerSelected])
+		return
+	case ctrlDown() && rl.IsKeyPressed(rl.KeyH):
+		ui.PickerShowHidden = !ui.PickerShowHidden
+		ui.PickerSelected = 0
+		return
+	}
+
+	if (rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown)) && ui.PickerSelected < len(entries)-1 {
+		ui.PickerSelected++
+	}
+	if (rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp)) && ui.PickerSelected > 0 {
+		ui.PickerSelected--
+	}
+
+	before := ui.PickerFilter.Text
+	ui.PickerFilter.HandleInput()
+	if ui.PickerFilter.Text != before {
+		ui.PickerSelected = 0
+		ui.PickerScroll = 0
+	}
+}
+
+func DrawFilePickerPanel(ui *UIState) {
+	if ui.PickerFilter == nil {
+		openFilePicker()
+	}
+	panelW := int32(min(900, windowWidth-40))
+	panelH := int32(min(600, windowHeight-editorTopPadding-editorBottomPadding-20))
+	panelX := int32(windowWidth)/2 - panelW/2
+	panelY := int32(editorTopPadding + 10)
+
+	listY := panelY + 112
+	maxVisible := max(1, int(panelH-112-76)/pickerRowHeight)
+	entries := pickerVisibleEntries()
+	ui.PickerSelected = max(0, min(ui.PickerSelected, len(entries)-1))
+
+	// keep the selection on screen, the wheel scrolls freely
+	if ui.PickerSelected < ui.PickerScroll {
+		ui.PickerScroll = ui.PickerSelected
+	}
+	if ui.PickerSelected >= ui.PickerScroll+maxVisible {
+		ui.PickerScroll = ui.PickerSelected - maxVisible + 1
+	}
+	mouseX, mouseY := rl.GetMouseX(), rl.GetMouseY()
+	if wheel := rl.GetMouseWheelMove(); wheel != 0 &&
+		mouseX >= panelX && mouseX <= panelX+panelW && mouseY >= panelY && mouseY <= panelY+panelH {
+		ui.PickerScroll -= int(wheel * 3)
+	}
+	ui.PickerScroll = max(0, min(ui.PickerScroll, len(entries)-maxVisible))
+
+	// draw shadow
+	drawShadow(float32(panelX), float32(panelY), float32(panelW), float32(panelH), 4, 8)
+
+	// draw panel
+	rl.DrawRectangle(panelX, panelY, panelW, panelH, ModernMedium)
+	rl.DrawRectangleLines(panelX, panelY, panelW, panelH, ModernBorder)
+
+	// header section
+	rl.DrawRectangle(panelX, panelY, panelW, 50, ModernDark)
+	DrawText("File Picker", int(panelX)+16, int(panelY)+10, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+	pathChars := int(panelW-32)/CHAR_IMAGE_WIDTH - 6
+	DrawText("Path: "+clampPathLeft(ui.CurrentPath, pathChars), int(panelX)+16, int(panelY)+30, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+
+	// filter and hidden toggle
+	ui.PickerFilter.Rect = rl.NewRectangle(float32(panelX+16), float32(panelY+58), float32(panelW-32-130), 28)
+	ui.PickerFilter.Draw()
+	if ui.PickerFilter.Text == "" {
+		DrawText("Filter...", ui.PickerFilter.textX(), int(panelY)+58+7, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+	}
+	hiddenLabel := "Hidden: off"
+	if ui.PickerShowHidden {
+		hiddenLabel = "Hidden: on"
+	}
+	if DrawModernButton(hiddenLabel, panelX+panelW-16-120, panelY+60, 120, 24, ModernText, ModernAccent, ModernLight, ModernDark, true) {
+		ui.PickerShowHidden = !ui.PickerShowHidden
+	}
+
+	// column headers, click to sort
+	sizeW := int32(10 * CHAR_IMAGE_WIDTH)
+	dateW := int32(len(pickerDateFormat) * CHAR_IMAGE_WIDTH)
+	dateX := panelX + panelW - 28 - dateW
+	sizeX := dateX - 16 - sizeW
+	nameX := panelX + 16
+	headerY := panelY + 92
+	for _, col := range []struct {
+		label, sort string
+		x, w        int32
+	}{
+		{"Name", "name", nameX, sizeX - nameX - 8},
+		{"Size", "size", sizeX, sizeW},
+		{"Modified", "mtime", dateX, dateW},
+	} {
+		label := col.label
+		if ui.PickerSort == col.sort {
+			if ui.PickerSortDesc {
+				label += " v"
+			} else {
+				label += " ^"
+			}
+		}
+		if DrawModernButton(label, col.x-8, headerY-4, col.w+8, 20, ModernText, ModernAccent, ModernLight, ModernMedium, true) {
+			pickerSetSort(col.sort)
+		}
+	}
+	rl.DrawRectangle(panelX+8, listY-2, panelW-16, 1, ModernBorder)
+
+	// file/folder list
+	nameChars := int(sizeX-nameX-16) / CHAR_IMAGE_WIDTH
+	for i := 0; i < maxVisible; i++ {
+		idx := ui.PickerScroll + i
+		if idx >= len(entries) {
+			break
+		}
+		entry := entries[idx]
+		y := listY + int32(i*pickerRowHeight)
+		hover := mouseX >= panelX+8 && mouseX < panelX+panelW-20 && mouseY >= y && mouseY < y+pickerRowHeight
+
+		if idx == ui.PickerSelected {
+			rl.DrawRectangle(panelX+8, y, panelW-28, pickerRowHeight, ModernLight)
+		} else if hover {
+			rl.DrawRectangle(panelX+8, y, panelW-28, pickerRowHeight, ModernDarkButton)
+		}
+
+		// different styling for folders vs files
+		name, nameColor := entry.Name, "ui.text"
+		if entry.IsFolder {
+			name, nameColor = entry.Name+"/", "ui.accent"
+		}
+		if len(name) > nameChars {
+			name = name[:max(0, nameChars-3)] + "..."
+		}
+		textY := int(y) + pickerRowHeight/2 - CHAR_IMAGE_HEIGHT/2
+		DrawText(name, int(nameX), textY, CHAR_IMAGE_WIDTH, rl.DrawPixel, nameColor)
+
+		if !entry.IsFolder {
+			size := formatFileSize(entry.Size)
+			DrawText(size, int(sizeX+sizeW)-len(size)*CHAR_IMAGE_WIDTH, textY, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		}
+		if !entry.ModTime.IsZero() {
+			DrawText(entry.ModTime.Format(pickerDateFormat), int(dateX), textY, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		}
+
+		if hover && !mouseBlocked && ui.PickerAction == "" && rl.IsMouseButtonPressed(rl.MouseRightButton) {
+			ui.PickerSelected = idx
+			openContextMenu(pickerContextItems(&entry))
+		}
+
+		// click selects, a second click on the same row within 0.4s opens it
+		if hover && !mouseBlocked && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+			now := rl.GetTime()
+			if pickerLastClick.index == idx && now-pickerLastClick.time < 0.4 {
+				pickerLastClick.time = 0
+				pickerActivate(entry)
+				return
+			}
+			ui.PickerSelected = idx
+			pickerLastClick.index = idx
+			pickerLastClick.time = now
+		}
+	}
+	// right-click below the entries
+	listH := int32(maxVisible * pickerRowHeight)
+	if !mouseBlocked && ui.PickerAction == "" && !modalOpen() && rl.IsMouseButtonPressed(rl.MouseRightButton) &&
+		mouseInRect(panelX+8, listY, panelW-28, listH) {
+		openContextMenu(pickerContextItems(nil))
+	}
+	if len(entries) == 0 {
+		DrawText("Nothing here", int(nameX), int(listY)+4, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+	}
+
+	// scroll bar
+	if len(entries) > maxVisible {
+		scrollBarX := panelX + panelW - 16
+		scrollBarH := int32(maxVisible * pickerRowHeight)
+		rl.DrawRectangle(scrollBarX, listY, 6, scrollBarH, ScrollTrackColor)
+
+		thumbHeight := max(20, scrollBarH*int32(maxVisible)/int32(len(entries)))
+		maxScroll := len(entries) - maxVisible
+		thumbY := listY + int32(float32(ui.PickerScroll)/float32(maxScroll)*float32(scrollBarH-thumbHeight))
+		rl.DrawRectangle(scrollBarX, thumbY, 6, thumbHeight, ScrollThumbColor)
+	}
+
+	// operations toolbar, replaced by the prompt while one is running
+	toolbarY := panelY + panelH - 66
+	if ui.PickerAction != "" {
+		drawPickerActionBar(panelX+16, toolbarY, panelW-32)
+	} else {
+		drawPickerToolbar(panelX+16, toolbarY)
+	}
+
+	// footer buttons
+	buttonY := panelY + panelH - 34
+	if ui.PickerError != "" {
+		msg := ui.PickerError
+		if maxChars := int(panelW-16-86-166) / CHAR_IMAGE_WIDTH; len(msg) > maxChars {
+			msg = msg[:max(0, maxChars-3)] + "..."
+		}
+		DrawText(msg, int(panelX)+86, int(buttonY)+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.danger")
+	}
+	if filepath.Dir(ui.CurrentPath) != ui.CurrentPath {
+		if DrawModernButton("Up", panelX+16, buttonY, 60, 24, ModernText, ModernAccent, ModernLight, ModernDark, true) {
+			pickerGoUp()
+			return
+		}
+	}
+	if DrawModernButton("Close", panelX+panelW-86, buttonY, 70, 24, ModernText, ModernDanger, ModernLight, ModernDark, true) {
+		closeFilePicker()
+		return
+	}
+	if ui.PickerSelected < len(entries) {
+		label := "Open"
+		if entries[ui.PickerSelected].IsFolder {
+			label = "Enter"
+		}
+		if DrawModernButton(label, panelX+panelW-166, buttonY, 70, 24, ModernText, ModernSuccess, ModernLight, ModernDark, true) {
+			pickerActivate(entries[ui.PickerSelected])
+			return
+		}
+	}
+}
+
+// keeps the end of a long path, "...ect/src"
+func clampPathLeft(p string, maxChars int) string {
+	if len(p) <= maxChars || maxChars < 4 {
+		return p
+	}
+	return "..." + p[len(p)-maxChars+3:]
+}
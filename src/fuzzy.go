@@ -0,0 +1,110 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// fuzzy matching for the command palette: the pattern letters have to appear in
+// the text in order, matches at word starts and runs of consecutive letters rank
+// higher than letters scattered through the text.
+
+func isWordBoundary(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, ch := text[i-1], text[i]
+	switch prev {
+	case ' ', ':', '/', '\\', '_', '-', '.':
+		return true
+	}
+	// camelCase
+	return prev >= 'a' && prev <= 'z' && ch >= 'A' && ch <= 'Z'
+}
+
+// returns the score and the matched byte positions in text, ok is false when
+// the pattern does not match at all. An empty pattern matches everything.
+func fuzzyScore(pattern, text string) (int, []int, bool) {
+	pattern = strings.ToLower(strings.ReplaceAll(pattern, " ", ""))
+	if pattern == "" {
+		return 0, nil, true
+	}
+	lower := strings.ToLower(text)
+
+	positions := make([]int, 0, len(pattern))
+	score := 0
+	pi := 0
+	last := -1
+	for i := 0; i < len(lower) && pi < len(pattern); i++ {
+		if lower[i] != pattern[pi] {
+			continue
+		}
+		// prefer a later word start over a match in the middle of a word
+		if !isWordBoundary(text, i) && last != i-1 {
+			if j := nextBoundaryMatch(text, lower, pattern[pi], i); j != -1 && remainingMatches(lower, pattern[pi+1:], j+1) {
+				i = j
+			}
+		}
+
+		switch {
+		case isWordBoundary(text, i):
+			score += 10
+		case last == i-1:
+			score += 8
+		default:
+			score += 1
+		}
+		if last >= 0 {
+			score -= min(i-last-1, 5)
+		}
+		positions = append(positions, i)
+		last = i
+		pi++
+	}
+	if pi < len(pattern) {
+		return 0, nil, false
+	}
+	// shorter texts win ties
+	score -= len(text) / 10
+	return score, positions, true
+}
+
+func nextBoundaryMatch(text, lower string, ch byte, from int) int {
+	for j := from + 1; j < len(lower); j++ {
+		if lower[j] == ch && isWordBoundary(text, j) {
+			return j
+		}
+	}
+	return -1
+}
+
+// true when the rest of the pattern can still be found after position from
+func remainingMatches(lower, rest string, from int) bool {
+	for i := from; i < len(lower) && len(rest) > 0; i++ {
+		if lower[i] == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
+
+type fuzzyMatch struct {
+	Index     int
+	Score     int
+	Positions []int
+}
+
+// matches every candidate against the pattern, best first. Equal scores keep the
+// original order.
+func fuzzyFilter(pattern string, candidates []string) []fuzzyMatch {
+	var matches []fuzzyMatch
+	for i, c := range candidates {
+		if score, positions, ok := fuzzyScore(pattern, c); ok {
+			matches = append(matches, fuzzyMatch{Index: i, Score: score, Positions: positions})
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].Score > matches[b].Score
+	})
+	return matches
+}
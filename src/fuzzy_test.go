@@ -0,0 +1,55 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		ok            bool
+		positions     []int
+	}{
+		{"", "File: Open", true, nil},
+		{"fo", "File: Open", true, []int{0, 6}},
+		{"FO", "file: open", true, []int{0, 6}},
+		{"f o", "File: Open", true, []int{0, 6}},
+		{"gc", "getCount", true, []int{0, 3}},
+		{"open", "File: Open", true, []int{6, 7, 8, 9}},
+		{"po", "File: Open", false, nil},
+		{"xyz", "File: Open", false, nil},
+	}
+	for _, tt := range tests {
+		_, positions, ok := fuzzyScore(tt.pattern, tt.text)
+		if ok != tt.ok || !slices.Equal(positions, tt.positions) {
+			t.Errorf("fuzzyScore(%q, %q) = %v, %v, want %v, %v", tt.pattern, tt.text, positions, ok, tt.positions, tt.ok)
+		}
+	}
+}
+
+func TestFuzzyFilterOrder(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		candidates []string
+		want       []int // indexes, best first
+	}{
+		// word starts beat letters inside words
+		{"fs", []string{"View: Refresh", "File: Save"}, []int{1, 0}},
+		// a run of letters beats scattered ones
+		{"abc", []string{"xaxbxcx", "xabcxxx"}, []int{1, 0}},
+		// shorter texts win ties, equal scores keep the order
+		{"go", []string{"Go: Start of Document", "Go: End"}, []int{1, 0}},
+		{"x", []string{"x", "x"}, []int{0, 1}},
+		{"q", []string{"File: Open"}, nil},
+	}
+	for _, tt := range tests {
+		var got []int
+		for _, m := range fuzzyFilter(tt.pattern, tt.candidates) {
+			got = append(got, m.Index)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("fuzzyFilter(%q, %q) = %v, want %v", tt.pattern, tt.candidates, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strconv"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var lineNumberModes = []string{"off", "absolute", "relative", "hybrid"}
+
+// switches to the next line number mode, used by the Ctrl+L shortcut
+func cycleLineNumberMode() {
+	next := 0
+	for i, m := range lineNumberModes {
+		if m == settings.LineNumbers {
+			next = (i + 1) % len(lineNumberModes)
+			break
+		}
+	}
+	settings.LineNumbers = lineNumberModes[next]
+	editorStatus = "Line numbers: " + settings.LineNumbers
+	ensureCursorVisible(cursor)
+}
+
+func setLineNumberMode(mode string) {
+	settings.LineNumbers = mode
+	editorStatus = "Line numbers: " + mode
+	ensureCursorVisible(cursor)
+}
+
+// number of digits the gutter has room for, at least 2 so it doesn't jump around on small files
+func gutterDigits() int {
+	digits := len(strconv.Itoa(usedRows))
+	if digits < 2 {
+		digits = 2
+	}
+	return digits
+}
+
+// width of the line number gutter in pixels, grows with the digit count of usedRows
+func gutterWidth() int {
+	if settings.LineNumbers == "off" || settings.LineNumbers == "" {
+		return 0
+	}
+	return (gutterDigits() + 1) * CHAR_IMAGE_WIDTH
+}
+
+// x position where the text of the active pane starts (after padding and gutter)
+func textAreaX() int {
+	return activePane.x + editorXPadding + gutterWidth()
+}
+
+// x position where the text area of the active pane ends (before the minimap)
+func textAreaRight() int {
+	return activePane.x + activePane.w - editorXPadding - minimapWidth()
+}
+
+// y position of the first text row of the active pane
+func textAreaY() int {
+	return activePane.y
+}
+
+// the number shown next to line y for the current mode
+func lineNumberLabel(y int) string {
+	distance := y - cursor.y
+	if distance < 0 {
+		distance = -distance
+	}
+
+	switch settings.LineNumbers {
+	case "relative":
+		return strconv.Itoa(distance)
+	case "hybrid":
+		if distance == 0 {
+			return strconv.Itoa(y + 1)
+		}
+		return strconv.Itoa(distance)
+	default:
+		return strconv.Itoa(y + 1)
+	}
+}
+
+func drawGutter(rows []visualRow) {
+	width := gutterWidth()
+	if width == 0 {
+		return
+	}
+
+	rl.DrawRectangle(int32(activePane.x), int32(activePane.y), int32(editorXPadding+width-CHAR_IMAGE_WIDTH/2),
+		int32(activePane.h), EditorGutterColor)
+
+	digits := gutterDigits()
+	for i, row := range rows {
+		// wrapped lines only get a number on their first row
+		if !row.first {
+			continue
+		}
+		label := lineNumberLabel(row.line)
+		color := "editor.lineNumber"
+		if row.line == cursor.y {
+			color = "editor.lineNumberActive"
+		}
+		// right align the numbers
+		x := activePane.x + editorXPadding + (digits-len(label))*CHAR_IMAGE_WIDTH
+		DrawText(label, x, i*CHAR_IMAGE_HEIGHT+textAreaY()+editorYPadding,
+			CHAR_IMAGE_WIDTH, rl.DrawPixel, color)
+	}
+}
+
+// selects the whole line y, used when a line number is clicked
+func selectLine(y int) {
+	if y >= usedRows {
+		y = usedRows - 1
+	}
+	if y < 0 {
+		return
+	}
+
+	width := getRowWidth(y)
+	selection.reset()
+	selection.Active = width > 0
+	selection.StartX = 0
+	selection.StartY = y
+	selection.EndX = width - 1
+	selection.EndY = y
+
+	cursor.y = y
+	cursor.x = 0
+	ensureCursorVisible(cursor)
+}
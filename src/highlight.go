@@ -0,0 +1,466 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// bundled language definitions, users can add/override them in <config>/languages
+//
+//go:embed languages/*.json
+var bundledLanguages embed.FS
+
+type TokenKind uint8
+
+const (
+	TokenDefault TokenKind = iota
+	TokenKeyword
+	TokenType
+	TokenBuiltin
+	TokenConstant
+	TokenString
+	TokenNumber
+	TokenComment
+	TokenOperator
+	TokenFunction
+	TokenVariable
+	TokenHeading
+	TokenEmphasis
+	TokenLink
+	TokenCode
+	tokenKindCount
+)
+
+// names used in the language files and as theme keys
+var tokenKindNames = [tokenKindCount]string{
+	"default", "keyword", "type", "builtin", "constant", "string", "number", "comment",
+	"operator", "function", "variable", "heading", "emphasis", "link", "code",
+}
+
+func tokenKindFromName(name string) (TokenKind, bool) {
+	if name == "" {
+		return TokenDefault, true
+	}
+	for i, n := range tokenKindNames {
+		if n == name {
+			return TokenKind(i), true
+		}
+	}
+	return TokenDefault, false
+}
+
+// color name (see colorMap) used to draw a token
+func tokenColorName(kind TokenKind) string {
+	if kind == TokenDefault {
+		return "editor.text"
+	}
+	return "syntax." + tokenKindNames[kind]
+}
+
+// ------------------------------------------------------------------------------------
+// Language definitions
+//
+// A definition is a small state machine: every state has an ordered list of regex
+// rules, the first rule matching at the current position wins, colors the matched
+// text and may push a new state or pop back to the previous one. The state stack at
+// the end of a line is carried to the next line (block comments, fenced code...).
+//
+//	{
+//	  "name": "Go",
+//	  "extensions": [".go"],
+//	  "states": {
+//	    "root":         {"rules": [{"match": "/\\*", "token": "comment", "push": "blockComment"}]},
+//	    "blockComment": {"token": "comment", "rules": [{"match": "\\*/", "token": "comment", "pop": true}]}
+//	  }
+//	}
+
+type languageFile struct {
+	Name        string               `json:"name"`
+	Extensions  []string             `json:"extensions"`
+	Filenames   []string             `json:"filenames"`
+	FirstLine   string               `json:"firstLine"`   // regex tested on line 1 (shebangs)
+	WordPattern string               `json:"wordPattern"` // what unmatched text is skipped by
+	States      map[string]stateFile `json:"states"`
+}
+
+type stateFile struct {
+	Token      string     `json:"token"`      // color of text no rule matched
+	SingleLine bool       `json:"singleLine"` // state is popped at the end of the line
+	Rules      []ruleFile `json:"rules"`
+}
+
+type ruleFile struct {
+	Match string `json:"match"`
+	Token string `json:"token"`
+	Push  string `json:"push"`
+	Pop   bool   `json:"pop"`
+	Bol   bool   `json:"bol"` // only match at the beginning of the line
+}
+
+type Language struct {
+	Name       string
+	Extensions []string
+	Filenames  []string
+	firstLine  *regexp.Regexp
+	word       *regexp.Regexp
+	states     []langState // states[0] is "root"
+}
+
+type langState struct {
+	name       string
+	token      TokenKind
+	singleLine bool
+	rules      []langRule
+}
+
+type langRule struct {
+	re    *regexp.Regexp
+	token TokenKind
+	push  int // -1 when the rule does not push
+	pop   bool
+	bol   bool
+}
+
+var languages []*Language
+
+func compileLanguage(lf languageFile) (*Language, error) {
+	if lf.Name == "" {
+		return nil, fmt.Errorf("language without a name")
+	}
+	if _, ok := lf.States["root"]; !ok {
+		return nil, fmt.Errorf("%s: missing \"root\" state", lf.Name)
+	}
+
+	lang := &Language{Name: lf.Name, Extensions: lf.Extensions, Filenames: lf.Filenames}
+
+	var err error
+	if lf.FirstLine != "" {
+		if lang.firstLine, err = regexp.Compile(lf.FirstLine); err != nil {
+			return nil, fmt.Errorf("%s: firstLine: %v", lf.Name, err)
+		}
+	}
+	wordPattern := lf.WordPattern
+	if wordPattern == "" {
+		wordPattern = `[A-Za-z0-9_]+`
+	}
+	if lang.word, err = regexp.Compile(`^(?:` + wordPattern + `)`); err != nil {
+		return nil, fmt.Errorf("%s: wordPattern: %v", lf.Name, err)
+	}
+
+	// root first so it gets index 0, the rest sorted to keep indexes stable
+	names := []string{"root"}
+	for name := range lf.States {
+		if name != "root" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names[1:])
+
+	for _, name := range names {
+		sf := lf.States[name]
+		st := langState{name: name, singleLine: sf.SingleLine}
+		var ok bool
+		if st.token, ok = tokenKindFromName(sf.Token); !ok {
+			return nil, fmt.Errorf("%s: state %s: unknown token %q", lf.Name, name, sf.Token)
+		}
+
+		for _, rf := range sf.Rules {
+			re, err := regexp.Compile(`^(?:` + rf.Match + `)`)
+			if err != nil {
+				return nil, fmt.Errorf("%s: state %s: %v", lf.Name, name, err)
+			}
+			rule := langRule{re: re, push: -1, pop: rf.Pop, bol: rf.Bol}
+			if rule.token, ok = tokenKindFromName(rf.Token); !ok {
+				return nil, fmt.Errorf("%s: state %s: unknown token %q", lf.Name, name, rf.Token)
+			}
+			if rf.Push != "" {
+				rule.push = slices.Index(names, rf.Push)
+				if rule.push == -1 {
+					return nil, fmt.Errorf("%s: state %s: push to unknown state %q", lf.Name, name, rf.Push)
+				}
+			}
+			st.rules = append(st.rules, rule)
+		}
+		lang.states = append(lang.states, st)
+	}
+
+	return lang, nil
+}
+
+func parseLanguage(data []byte) (*Language, error) {
+	var lf languageFile
+	if err := json.Unmarshal(data, &lf); err != nil {
+		return nil, err
+	}
+	return compileLanguage(lf)
+}
+
+// loads the bundled definitions, then the ones from the config folder.
+// a user definition with the same name replaces the bundled one
+func loadLanguages() {
+	languages = nil
+
+	bundled, _ := bundledLanguages.ReadDir("languages")
+	for _, f := range bundled {
+		data, err := bundledLanguages.ReadFile("languages/" + f.Name())
+		if err != nil {
+			continue
+		}
+		addLanguage(data, f.Name())
+	}
+
+	userDir := configPath("languages")
+	userFiles, err := os.ReadDir(userDir)
+	if err != nil {
+		return
+	}
+	for _, f := range userFiles {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(userDir, f.Name()))
+		if err != nil {
+			notifyError("Could not read language file: %v", err)
+			continue
+		}
+		addLanguage(data, f.Name())
+	}
+}
+
+func addLanguage(data []byte, source string) {
+	lang, err := parseLanguage(data)
+	if err != nil {
+		notifyError("Language %s not loaded: %v", source, err)
+		return
+	}
+	for i, l := range languages {
+		if strings.EqualFold(l.Name, lang.Name) {
+			languages[i] = lang
+			return
+		}
+	}
+	languages = append(languages, lang)
+}
+
+// picks a language by file name, extension, then by the first line of the file
+func languageForFile(path string, firstLine []byte) *Language {
+	base := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(base))
+	for _, l := range languages {
+		if slices.Contains(l.Filenames, base) {
+			return l
+		}
+	}
+	if ext != "" {
+		for _, l := range languages {
+			if slices.Contains(l.Extensions, ext) {
+				return l
+			}
+		}
+	}
+	for _, l := range languages {
+		if l.firstLine != nil && l.firstLine.Match(firstLine) {
+			return l
+		}
+	}
+	return nil
+}
+
+// tokenizes a single line starting with the given state stack, returns a token kind
+// per byte and the state stack at the end of the line
+func (lang *Language) tokenizeLine(line []byte, start []int) ([]TokenKind, []int) {
+	kinds := make([]TokenKind, len(line))
+	stack := slices.Clone(start)
+	if len(stack) == 0 {
+		stack = []int{0}
+	}
+
+	pos := 0
+	emptySteps := 0 // guards against push/pop loops on empty matches
+	for pos < len(line) {
+		state := &lang.states[stack[len(stack)-1]]
+		matched := false
+
+		for _, r := range state.rules {
+			if r.bol && pos != 0 {
+				continue
+			}
+			loc := r.re.FindIndex(line[pos:])
+			if loc == nil {
+				continue
+			}
+			n := loc[1]
+			if n == 0 {
+				if (r.push == -1 && !r.pop) || emptySteps > 8 {
+					continue
+				}
+				emptySteps++
+			} else {
+				emptySteps = 0
+			}
+
+			for i := pos; i < pos+n; i++ {
+				kinds[i] = r.token
+			}
+			pos += n
+			if r.pop && len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			if r.push != -1 {
+				stack = append(stack, r.push)
+			}
+			matched = true
+			break
+		}
+
+		if !matched {
+			// skip a whole word so keywords never match in the middle of one
+			n := 1
+			if loc := lang.word.FindIndex(line[pos:]); loc != nil && loc[1] > 0 {
+				n = loc[1]
+			}
+			for i := pos; i < pos+n; i++ {
+				kinds[i] = state.token
+			}
+			pos += n
+			emptySteps = 0
+		}
+	}
+
+	for len(stack) > 1 && lang.states[stack[len(stack)-1]].singleLine {
+		stack = stack[:len(stack)-1]
+	}
+	return kinds, stack
+}
+
+// ------------------------------------------------------------------------------------
+// Incremental highlighter
+//
+// Lines are tokenized lazily, only up to the last line that gets drawn. Every cached
+// line keeps a hash of its content and the state it started in, edits only move
+// validUpTo back, lines after it are checked against the cache again and only the
+// ones that really changed (content or start state) are tokenized again.
+
+type lineHighlight struct {
+	hash  uint64
+	start []int
+	end   []int
+	kinds []TokenKind
+}
+
+type Highlighter struct {
+	file      string
+	lang      *Language
+	lines     []lineHighlight
+	validUpTo int
+}
+
+var highlighter = &Highlighter{}
+
+func (h *Highlighter) reset() {
+	h.lines = nil
+	h.validUpTo = 0
+}
+
+// marks line y (and everything after it) as possibly changed
+func (h *Highlighter) invalidateFrom(y int) {
+	if y < 0 {
+		y = 0
+	}
+	if y < h.validUpTo {
+		h.validUpTo = y
+	}
+}
+
+// makes sure lines [0, endY) of the current buffer are tokenized
+func (h *Highlighter) update(endY int) {
+	if h.file != currentFile {
+		h.file = currentFile
+		h.lang = languageForFile(currentFile, rowContent(0))
+		h.reset()
+	}
+	if h.lang == nil {
+		return
+	}
+
+	if endY > usedRows {
+		endY = usedRows
+	}
+	if len(h.lines) > usedRows {
+		h.lines = h.lines[:usedRows]
+	}
+	for len(h.lines) < usedRows {
+		h.lines = append(h.lines, lineHighlight{})
+	}
+	if h.validUpTo > len(h.lines) {
+		h.validUpTo = len(h.lines)
+	}
+
+	for y := h.validUpTo; y < endY; y++ {
+		var start []int
+		if y > 0 {
+			start = h.lines[y-1].end
+		}
+
+		content := rowContent(y)
+		sum := hashBytes(content)
+
+		line := &h.lines[y]
+		if line.kinds != nil && line.hash == sum && slices.Equal(line.start, start) {
+			continue
+		}
+		line.kinds, line.end = h.lang.tokenizeLine(content, start)
+		line.hash = sum
+		line.start = start
+	}
+	if endY > h.validUpTo {
+		h.validUpTo = endY
+	}
+}
+
+// token kinds of line y, nil when the buffer has no language or the line is not tokenized
+func (h *Highlighter) lineKinds(y int) []TokenKind {
+	if h.lang == nil || y < 0 || y >= h.validUpTo || y >= len(h.lines) {
+		return nil
+	}
+	return h.lines[y].kinds
+}
+
+// color name for the character at x on a line previously returned by lineKinds
+func colorForKinds(kinds []TokenKind, x int) string {
+	if x < len(kinds) {
+		return tokenColorName(kinds[x])
+	}
+	return "editor.text"
+}
+
+func hashBytes(b []byte) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write(b)
+	return hasher.Sum64()
+}
+
+// returns the characters of a row without the trailing '\n' and empty cells
+func rowContent(y int) []byte {
+	if y < 0 || y >= len(textGrid) {
+		return nil
+	}
+	row := textGrid[y]
+	end := 0
+	for i, ch := range row {
+		if ch == '\n' {
+			break
+		}
+		if ch != 0 {
+			end = i + 1
+		}
+	}
+	return row[:end]
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// .gitignore style matching for the project file walkers. Supported: comments,
+// "!" negation, trailing "/" for folders only, leading or inner "/" anchoring the
+// pattern to the folder of the .gitignore, "*", "?", "[...]" and "**".
+
+type ignoreRule struct {
+	base    string // folder the pattern is relative to, "" for the root
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+type ignoreSet struct {
+	rules []ignoreRule
+}
+
+// folders that are never worth walking
+var alwaysIgnored = []string{".git/"}
+
+func newIgnoreSet(patterns []string) *ignoreSet {
+	s := &ignoreSet{}
+	for _, p := range alwaysIgnored {
+		s.add("", p)
+	}
+	for _, p := range patterns {
+		s.add("", p)
+	}
+	return s
+}
+
+// adds one gitignore line, base is the slash separated folder it came from
+func (s *ignoreSet) add(base, line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, "\\")
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	// without a slash the pattern matches the name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return
+	}
+	rule.re = re
+	s.rules = append(s.rules, rule)
+}
+
+// reads <dir>/.gitignore, rel is the folder relative to the walk root
+func (s *ignoreSet) addFile(dir, rel string) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s.add(rel, scanner.Text())
+	}
+}
+
+// rel is slash separated and relative to the walk root, the last matching rule wins
+func (s *ignoreSet) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range s.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		p := rel
+		if r.base != "" {
+			var ok bool
+			if p, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+				continue
+			}
+		}
+		if r.re.MatchString(p) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case ch == '*':
+			sb.WriteString("[^/]*")
+		case ch == '?':
+			sb.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end == -1 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	return sb.String()
+}
+
+// slash separated path of p relative to root
+func relSlash(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return path.Clean(filepath.ToSlash(rel))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	s := newIgnoreSet([]string{
+		"# comment",
+		"*.o",
+		"!keep.o",
+		"build/",
+		"/root.txt",
+		"docs/**/*.md",
+		"a?c",
+		"[0-9].log",
+	})
+	s.add("sub", "*.tmp")
+	s.add("sub", "!important.tmp")
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"main.o", false, true},
+		{"src/main.o", false, true},
+		{"keep.o", false, false},
+		{"src/keep.o", false, false},
+		{"build", true, true},
+		{"src/build", true, true},
+		{"build", false, false}, // folders only
+		{"root.txt", false, true},
+		{"src/root.txt", false, false}, // anchored to the root
+		{"docs/a.md", false, true},
+		{"docs/a/b/c.md", false, true},
+		{"src/docs/a.md", false, false},
+		{"abc", false, true},
+		{"abbc", false, false},
+		{"1.log", false, true},
+		{"x.log", false, false},
+		{".git", true, true},
+		{"sub/a.tmp", false, true},
+		{"sub/deep/a.tmp", false, true},
+		{"sub/important.tmp", false, false},
+		{"a.tmp", false, false}, // only below sub
+		{"# comment", false, false},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := s.ignored(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestPathMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		ok            bool
+		positions     []int
+	}{
+		{"", "src/main.go", true, nil},
+		{"main", "src/main.go", true, []int{4, 5, 6, 7}},
+		{"src/ui", "src/ui.go", true, []int{0, 1, 2, 4, 5}},
+		{"/src/ui/", "src/ui.go", true, []int{0, 1, 2, 4, 5}},
+		{"ui/src", "src/ui.go", false, nil}, // parts match later segments only
+		{"zz", "src/ui.go", false, nil},
+	}
+	for _, tt := range tests {
+		_, positions, ok := pathMatch(tt.pattern, tt.path)
+		if ok != tt.ok || !slices.Equal(positions, tt.positions) {
+			t.Errorf("pathMatch(%q, %q) = %v, %v, want %v, %v", tt.pattern, tt.path, positions, ok, tt.positions, tt.ok)
+		}
+	}
+
+	// the file name wins over the same letters spread across folders
+	byName, _, _ := pathMatch("ui", "src/ui.go")
+	byFolders, _, _ := pathMatch("ui", "src/utils/index.go")
+	if byName <= byFolders {
+		t.Errorf("pathMatch(ui): src/ui.go scored %d, src/utils/index.go %d", byName, byFolders)
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// single line text field used by every modal: caret, selection with shift / mouse,
+// clipboard, word movement and per box history browsed with Up/Down
+type InputBox struct {
+	Rect     rl.Rectangle
+	Text     string
+	Focused  bool
+	MaxChars int
+	History  string // history list this box reads and adds to, empty for none
+
+	caret    int
+	anchor   int // other end of the selection, == caret when nothing is selected
+	scroll   int // first visible character
+	dragging bool
+
+	historyPos int // 1 based index in the history while browsing, 0 when editing
+	draft      string
+
+	repeat map[int32]int // frames every key has been held down
+}
+
+// previous entries per history name, newest last
+var inputHistory = map[string][]string{}
+
+const inputRepeatDelay = 25 // frames before a held key starts repeating
+const inputRepeatRate = 3   // frames between repeats
+
+// true when the key was pressed this frame or is held long enough to repeat
+func (box *InputBox) keyRepeat(key int32) bool {
+	if box.repeat == nil {
+		box.repeat = map[int32]int{}
+	}
+	if rl.IsKeyPressed(key) {
+		box.repeat[key] = 0
+		return true
+	}
+	if !rl.IsKeyDown(key) {
+		delete(box.repeat, key)
+		return false
+	}
+	box.repeat[key]++
+	n := box.repeat[key] - inputRepeatDelay
+	return n >= 0 && n%inputRepeatRate == 0
+}
+
+func (box *InputBox) hasSelection() bool {
+	return box.caret != box.anchor
+}
+
+func (box *InputBox) selectionRange() (int, int) {
+	return min(box.caret, box.anchor), max(box.caret, box.anchor)
+}
+
+func (box *InputBox) SelectedText() string {
+	start, end := box.selectionRange()
+	return box.Text[start:end]
+}
+
+func (box *InputBox) SelectAll() {
+	box.anchor = 0
+	box.caret = len(box.Text)
+}
+
+// replaces the text and puts the caret at its end
+func (box *InputBox) SetText(text string) {
+	box.Text = text
+	box.caret = len(text)
+	box.anchor = box.caret
+}
+
+// moves the caret, keeping the selection anchor when extend is set
+func (box *InputBox) moveCaret(pos int, extend bool) {
+	box.caret = max(0, min(pos, len(box.Text)))
+	if !extend {
+		box.anchor = box.caret
+	}
+}
+
+func (box *InputBox) deleteSelection() {
+	start, end := box.selectionRange()
+	box.Text = box.Text[:start] + box.Text[end:]
+	box.caret = start
+	box.anchor = start
+}
+
+// inserts at the caret replacing the selection, cut to MaxChars
+func (box *InputBox) insert(s string) {
+	if box.hasSelection() {
+		box.deleteSelection()
+	}
+	if box.MaxChars > 0 {
+		room := box.MaxChars - len(box.Text)
+		if room <= 0 {
+			return
+		}
+		if len(s) > room {
+			s = s[:room]
+		}
+	}
+	box.Text = box.Text[:box.caret] + s + box.Text[box.caret:]
+	box.caret += len(s)
+	box.anchor = box.caret
+	box.historyPos = 0
+}
+
+// start of the word left of pos, skipping spaces first
+func (box *InputBox) wordLeft(pos int) int {
+	for pos > 0 && !isWordChar(box.Text[pos-1]) {
+		pos--
+	}
+	for pos > 0 && isWordChar(box.Text[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+func (box *InputBox) wordRight(pos int) int {
+	for pos < len(box.Text) && !isWordChar(box.Text[pos]) {
+		pos++
+	}
+	for pos < len(box.Text) && isWordChar(box.Text[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// adds the current text to the box history, called when a modal accepts it
+func (box *InputBox) AddToHistory() {
+	if box.History == "" || strings.TrimSpace(box.Text) == "" {
+		return
+	}
+	entries := inputHistory[box.History]
+	if n := len(entries); n > 0 && entries[n-1] == box.Text {
+		return
+	}
+	inputHistory[box.History] = append(entries, box.Text)
+}
+
+func (box *InputBox) browseHistory(dir int) {
+	entries := inputHistory[box.History]
+	if len(entries) == 0 {
+		return
+	}
+	if box.historyPos == 0 {
+		if dir > 0 {
+			return
+		}
+		box.draft = box.Text
+		box.historyPos = len(entries) + 1
+	}
+	box.historyPos += dir
+	switch {
+	case box.historyPos < 1:
+		box.historyPos = 1
+	case box.historyPos > len(entries):
+		// past the newest entry, back to what was being typed
+		box.historyPos = 0
+		box.SetText(box.draft)
+		return
+	}
+	box.SetText(entries[box.historyPos-1])
+}
+
+func (box *InputBox) textX() int {
+	return int(box.Rect.X) + 12
+}
+
+func (box *InputBox) visibleChars() int {
+	return max(1, (int(box.Rect.Width)-24)/CHAR_IMAGE_WIDTH)
+}
+
+// character index under the mouse
+func (box *InputBox) indexAt(mouseX int) int {
+	col := (mouseX - box.textX() + CHAR_IMAGE_WIDTH/2) / CHAR_IMAGE_WIDTH
+	return max(0, min(box.scroll+col, len(box.Text)))
+}
+
+func (box *InputBox) Draw() {
+	// draw shadow
+	drawShadow(box.Rect.X, box.Rect.Y, box.Rect.Width, box.Rect.Height, 2, 3)
+
+	bg := ModernMedium
+	if box.Focused {
+		bg = ModernLight
+	}
+
+	// draw panel
+	rl.DrawRectangle(int32(box.Rect.X), int32(box.Rect.Y), int32(box.Rect.Width), int32(box.Rect.Height), bg)
+
+	// draw border
+	borderColor := ModernLight
+	if box.Focused {
+		borderColor = ModernAccent
+	}
+	rl.DrawRectangleLines(int32(box.Rect.X), int32(box.Rect.Y), int32(box.Rect.Width), int32(box.Rect.Height), borderColor)
+
+	// keep the caret inside the visible part
+	visible := box.visibleChars()
+	if box.caret < box.scroll {
+		box.scroll = box.caret
+	}
+	if box.caret > box.scroll+visible {
+		box.scroll = box.caret - visible
+	}
+	box.scroll = max(0, min(box.scroll, len(box.Text)))
+
+	textY := int(box.Rect.Y) + int(box.Rect.Height)/2 - CHAR_IMAGE_HEIGHT/2
+	end := min(len(box.Text), box.scroll+visible)
+
+	if box.hasSelection() {
+		start, stop := box.selectionRange()
+		start = max(start, box.scroll)
+		stop = min(stop, end)
+		if stop > start {
+			rl.DrawRectangle(int32(box.textX()+(start-box.scroll)*CHAR_IMAGE_WIDTH), int32(textY),
+				int32((stop-start)*CHAR_IMAGE_WIDTH), CHAR_IMAGE_HEIGHT, EditorSelectionColor)
+		}
+	}
+
+	DrawText(box.Text[box.scroll:end], box.textX(), textY, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+
+	// blinking caret
+	if box.Focused && int(rl.GetTime()*2)%2 == 0 {
+		caretX := box.textX() + (box.caret-box.scroll)*CHAR_IMAGE_WIDTH
+		rl.DrawRectangle(int32(caretX), int32(textY), 2, CHAR_IMAGE_HEIGHT, ModernText)
+	}
+}
+
+func (box *InputBox) HandleInput() {
+	// autofocus
+	box.Focused = true
+
+	if box.caret > len(box.Text) || box.anchor > len(box.Text) {
+		box.SetText(box.Text)
+	}
+
+	// mouse placement and drag selection
+	mouse := rl.GetMousePosition()
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && rl.CheckCollisionPointRec(mouse, box.Rect) {
+		box.moveCaret(box.indexAt(int(mouse.X)), shiftDown())
+		box.dragging = true
+	}
+	if box.dragging {
+		if rl.IsMouseButtonDown(rl.MouseLeftButton) {
+			box.moveCaret(box.indexAt(int(mouse.X)), true)
+		} else {
+			box.dragging = false
+		}
+	}
+
+	for char := rl.GetCharPressed(); char > 0; char = rl.GetCharPressed() {
+		if char >= 32 && char <= 126 {
+			box.insert(string(rune(char)))
+		}
+	}
+
+	ctrl := ctrlDown()
+	shift := shiftDown()
+
+	if ctrl {
+		switch {
+		case rl.IsKeyPressed(rl.KeyA):
+			box.SelectAll()
+		case rl.IsKeyPressed(rl.KeyC) && box.hasSelection():
+			editorClipboard = box.SelectedText()
+			writeClipboard(editorClipboard)
+		case rl.IsKeyPressed(rl.KeyX) && box.hasSelection():
+			editorClipboard = box.SelectedText()
+			writeClipboard(editorClipboard)
+			box.deleteSelection()
+		case rl.IsKeyPressed(rl.KeyV):
+			// single line, newlines become spaces
+			text := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(editorClipboard)
+			box.insert(text)
+		}
+	}
+
+	if box.keyRepeat(rl.KeyBackspace) {
+		switch {
+		case box.hasSelection():
+			box.deleteSelection()
+		case ctrl:
+			box.anchor = box.wordLeft(box.caret)
+			box.deleteSelection()
+		case box.caret > 0:
+			box.Text = box.Text[:box.caret-1] + box.Text[box.caret:]
+			box.moveCaret(box.caret-1, false)
+		}
+	}
+	if box.keyRepeat(rl.KeyDelete) {
+		switch {
+		case box.hasSelection():
+			box.deleteSelection()
+		case ctrl:
+			box.anchor = box.wordRight(box.caret)
+			box.deleteSelection()
+		case box.caret < len(box.Text):
+			box.Text = box.Text[:box.caret] + box.Text[box.caret+1:]
+		}
+	}
+
+	if box.keyRepeat(rl.KeyLeft) {
+		switch {
+		case ctrl:
+			box.moveCaret(box.wordLeft(box.caret), shift)
+		case box.hasSelection() && !shift:
+			start, _ := box.selectionRange()
+			box.moveCaret(start, false)
+		default:
+			box.moveCaret(box.caret-1, shift)
+		}
+	}
+	if box.keyRepeat(rl.KeyRight) {
+		switch {
+		case ctrl:
+			box.moveCaret(box.wordRight(box.caret), shift)
+		case box.hasSelection() && !shift:
+			_, end := box.selectionRange()
+			box.moveCaret(end, false)
+		default:
+			box.moveCaret(box.caret+1, shift)
+		}
+	}
+	if rl.IsKeyPressed(rl.KeyHome) {
+		box.moveCaret(0, shift)
+	}
+	if rl.IsKeyPressed(rl.KeyEnd) {
+		box.moveCaret(len(box.Text), shift)
+	}
+
+	if box.History != "" {
+		if box.keyRepeat(rl.KeyUp) {
+			box.browseHistory(-1)
+		}
+		if box.keyRepeat(rl.KeyDown) {
+			box.browseHistory(1)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// The keymap maps key sequences to command IDs. The defaults come from the
+// Keybinding of every registered command, <config>/keymap.json adds to them:
+//
+//	[
+//	  {"key": "Ctrl+K Ctrl+C", "command": "edit.copy"},
+//	  {"key": "Ctrl+Shift+S", "command": "-file.saveAs"}
+//	]
+//
+// a "-" in front of the command removes its binding for that key (or every
+// binding of the command when key is empty).
+//
+// A chord matches when its key is pressed with exactly its modifiers, Ctrl+Alt+Left
+// does not run Alt+Left. When several bindings match, the user keymap wins, then
+// the binding defined last. A binding
+// that is also the start of a longer sequence runs when the next key does not go
+// on with the sequence, or after keySequenceTimeout. Commands marked Repeat (the
+// cursor movement) run again while their key is held.
+
+type KeyChord struct {
+	Ctrl  bool
+	Shift bool
+	Alt   bool
+	Key   int32
+}
+
+type Binding struct {
+	Keys    []KeyChord
+	Label   string // as written, e.g. "Ctrl+K Ctrl+C"
+	Command string
+	User    bool // from keymap.json
+}
+
+var keymap []*Binding
+
+// chords of an unfinished sequence, e.g. Ctrl+K while waiting for the second key
+var pendingKeys []KeyChord
+
+// binding of pendingKeys when they are already complete, run unless the sequence goes on
+var pendingBinding *Binding
+var pendingSince time.Time
+
+const keySequenceTimeout = time.Second
+
+// problems found while loading keymap.json, shown in the bindings list
+var keymapErrors []string
+
+var keyNames = map[string]int32{
+	"enter": rl.KeyEnter, "esc": rl.KeyEscape, "escape": rl.KeyEscape, "tab": rl.KeyTab,
+	"backspace": rl.KeyBackspace, "delete": rl.KeyDelete, "insert": rl.KeyInsert, "space": rl.KeySpace,
+	"home": rl.KeyHome, "end": rl.KeyEnd, "pageup": rl.KeyPageUp, "pagedown": rl.KeyPageDown,
+	"up": rl.KeyUp, "down": rl.KeyDown, "left": rl.KeyLeft, "right": rl.KeyRight,
+	"f1": rl.KeyF1, "f2": rl.KeyF2, "f3": rl.KeyF3, "f4": rl.KeyF4, "f5": rl.KeyF5, "f6": rl.KeyF6,
+	"f7": rl.KeyF7, "f8": rl.KeyF8, "f9": rl.KeyF9, "f10": rl.KeyF10, "f11": rl.KeyF11, "f12": rl.KeyF12,
+	",": rl.KeyComma, ".": rl.KeyPeriod, "/": rl.KeySlash, ";": rl.KeySemicolon, "'": rl.KeyApostrophe,
+	"[": rl.KeyLeftBracket, "]": rl.KeyRightBracket, "\\": rl.KeyBackSlash, "-": rl.KeyMinus,
+	"=": rl.KeyEqual, "`": rl.KeyGrave,
+}
+
+// parses "Ctrl+Shift+P" style chords
+func parseKeyChord(s string) (KeyChord, error) {
+	var chord KeyChord
+	parts := strings.Split(s, "+")
+	// "Ctrl++" means the plus key, Shift and = on most keyboards
+	if strings.HasSuffix(s, "++") {
+		parts = append(strings.Split(strings.TrimSuffix(s, "++"), "+"), "shift", "=")
+	}
+
+	for i, part := range parts {
+		p := strings.ToLower(strings.TrimSpace(part))
+		if i < len(parts)-1 {
+			switch p {
+			case "ctrl", "control":
+				chord.Ctrl = true
+			case "shift":
+				chord.Shift = true
+			case "alt":
+				chord.Alt = true
+			default:
+				return chord, fmt.Errorf("unknown modifier %q in %q", part, s)
+			}
+			continue
+		}
+
+		if key, ok := keyNames[p]; ok {
+			chord.Key = key
+		} else if len(p) == 1 && p[0] >= 'a' && p[0] <= 'z' {
+			chord.Key = int32(rl.KeyA) + int32(p[0]-'a')
+		} else if len(p) == 1 && p[0] >= '0' && p[0] <= '9' {
+			chord.Key = int32(rl.KeyZero) + int32(p[0]-'0')
+		} else {
+			return chord, fmt.Errorf("unknown key %q in %q", part, s)
+		}
+	}
+	return chord, nil
+}
+
+// parses a sequence of chords separated by spaces, "Ctrl+K Ctrl+C"
+func parseKeySequence(s string) ([]KeyChord, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty key")
+	}
+	keys := make([]KeyChord, len(fields))
+	for i, f := range fields {
+		chord, err := parseKeyChord(f)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = chord
+	}
+	return keys, nil
+}
+
+func (k KeyChord) String() string {
+	var parts []string
+	if k.Ctrl {
+		parts = append(parts, "Ctrl")
+	}
+	if k.Shift {
+		parts = append(parts, "Shift")
+	}
+	if k.Alt {
+		parts = append(parts, "Alt")
+	}
+	name := ""
+	for n, key := range keyNames {
+		if key == k.Key && len(n) > len(name) {
+			name = n
+		}
+	}
+	switch {
+	case k.Key >= rl.KeyA && k.Key <= rl.KeyZ:
+		name = string(rune('A' + k.Key - rl.KeyA))
+	case k.Key >= rl.KeyZero && k.Key <= rl.KeyNine:
+		name = string(rune('0' + k.Key - rl.KeyZero))
+	case len(name) > 1:
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+	return strings.Join(append(parts, name), "+")
+}
+
+// true if the pressed chord is k, same key and the same modifiers
+func (k KeyChord) matches(pressed KeyChord) bool {
+	return k == pressed
+}
+
+func ctrlDown() bool  { return rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl) }
+func shiftDown() bool { return rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) }
+func altDown() bool   { return rl.IsKeyDown(rl.KeyLeftAlt) || rl.IsKeyDown(rl.KeyRightAlt) }
+
+func isModifierKey(key int32) bool {
+	switch key {
+	case rl.KeyLeftControl, rl.KeyRightControl, rl.KeyLeftShift, rl.KeyRightShift,
+		rl.KeyLeftAlt, rl.KeyRightAlt, rl.KeyLeftSuper, rl.KeyRightSuper:
+		return true
+	}
+	return false
+}
+
+type keymapEntry struct {
+	Key     string `json:"key"`
+	Command string `json:"command"`
+}
+
+// builds the keymap from the command defaults and <config>/keymap.json
+func loadKeymap() {
+	keymap = nil
+	keymapErrors = nil
+	pendingKeys = nil
+	pendingBinding = nil
+
+	for _, c := range commands {
+		if c.Keybinding == "" {
+			continue
+		}
+		keys, err := parseKeySequence(c.Keybinding)
+		if err != nil {
+			keymapErrors = append(keymapErrors, fmt.Sprintf("%s: %v", c.ID, err))
+			continue
+		}
+		keymap = append(keymap, &Binding{Keys: keys, Label: c.Keybinding, Command: c.ID})
+	}
+
+	data, err := os.ReadFile(configPath("keymap.json"))
+	if err != nil {
+		return
+	}
+	var entries []keymapEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		keymapErrors = append(keymapErrors, "keymap.json: "+err.Error())
+		notifyError("Could not read keymap.json: %v", err)
+		return
+	}
+
+	for _, e := range entries {
+		if id, ok := strings.CutPrefix(e.Command, "-"); ok {
+			unbind(e.Key, id)
+			continue
+		}
+		if _, ok := commandsByID[e.Command]; !ok {
+			keymapErrors = append(keymapErrors, fmt.Sprintf("%s: unknown command %q", e.Key, e.Command))
+			continue
+		}
+		keys, err := parseKeySequence(e.Key)
+		if err != nil {
+			keymapErrors = append(keymapErrors, fmt.Sprintf("%s: %v", e.Command, err))
+			continue
+		}
+		keymap = append(keymap, &Binding{Keys: keys, Label: sequenceLabel(keys), Command: e.Command, User: true})
+	}
+}
+
+func sequenceLabel(keys []KeyChord) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// removes the bindings of a command, for one key or all of them when key is empty
+func unbind(key, id string) {
+	var keys []KeyChord
+	if key != "" {
+		var err error
+		keys, err = parseKeySequence(key)
+		if err != nil {
+			keymapErrors = append(keymapErrors, fmt.Sprintf("-%s: %v", id, err))
+			return
+		}
+	}
+	kept := keymap[:0]
+	for _, b := range keymap {
+		if b.Command == id && (key == "" || sequenceLabel(b.Keys) == sequenceLabel(keys)) {
+			continue
+		}
+		kept = append(kept, b)
+	}
+	keymap = kept
+}
+
+// binding whose sequence is exactly the given chords, prefix is true when a longer
+// sequence starts with them. Chords match exactly, so all complete matches have the
+// same keys: a user binding wins over a default one, then the later binding.
+func lookupBinding(keys []KeyChord) (best *Binding, complete bool, prefix bool) {
+	for _, b := range keymap {
+		if len(b.Keys) < len(keys) {
+			continue
+		}
+		ok := true
+		for i, k := range keys {
+			if !b.Keys[i].matches(k) {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		if len(b.Keys) > len(keys) {
+			prefix = true
+			continue
+		}
+		if best == nil || b.User || !best.User {
+			best = b
+		}
+	}
+	return best, best != nil, prefix
+}
+
+// runs the command bound to the keys pressed this frame, returns true when the
+// key press was used (a command ran or a sequence is in progress)
+func dispatchKeybindings() bool {
+	used := false
+	for key := rl.GetKeyPressed(); key != 0; key = rl.GetKeyPressed() {
+		if isModifierKey(key) {
+			continue
+		}
+		if pressChord(KeyChord{Ctrl: ctrlDown(), Shift: shiftDown(), Alt: altDown(), Key: key}) {
+			used = true
+		}
+	}
+	if pendingBinding != nil && time.Since(pendingSince) >= keySequenceTimeout {
+		runPendingBinding()
+		used = true
+	}
+	if len(pendingKeys) == 0 && repeatKeybindings() {
+		used = true
+	}
+	return used
+}
+
+// adds a chord to the sequence being typed, true when it was used
+func pressChord(chord KeyChord) bool {
+	keys := append(pendingKeys, chord)
+	binding, complete, prefix := lookupBinding(keys)
+	switch {
+	case prefix:
+		// wait for the next chord, a longer sequence beats a complete shorter one
+		pendingKeys = keys
+		pendingBinding = binding
+		pendingSince = time.Now()
+		editorStatus = sequenceLabel(keys) + " was pressed, waiting for the next key..."
+	case complete:
+		pendingKeys = nil
+		pendingBinding = nil
+		runCommand(binding.Command)
+	case pendingBinding != nil:
+		// the sequence does not go on, run what was typed so far and start again
+		runPendingBinding()
+		pressChord(chord)
+	case len(pendingKeys) > 0:
+		editorStatus = sequenceLabel(keys) + " is not bound"
+		pendingKeys = nil
+	default:
+		return false
+	}
+	return true
+}
+
+func runPendingBinding() {
+	b := pendingBinding
+	pendingKeys = nil
+	pendingBinding = nil
+	editorStatus = ""
+	runCommand(b.Command)
+}
+
+// runs the Repeat commands again while their key is held
+func repeatKeybindings() bool {
+	used := false
+	seen := map[int32]bool{}
+	for _, b := range keymap {
+		key := b.Keys[0].Key
+		if len(b.Keys) != 1 || seen[key] || !rl.IsKeyPressedRepeat(key) {
+			continue
+		}
+		seen[key] = true
+		chord := KeyChord{Ctrl: ctrlDown(), Shift: shiftDown(), Alt: altDown(), Key: key}
+		binding, complete, _ := lookupBinding([]KeyChord{chord})
+		if !complete {
+			continue
+		}
+		if c, ok := commandsByID[binding.Command]; ok && c.Repeat {
+			runCommand(binding.Command)
+			used = true
+		}
+	}
+	return used
+}
+
+// active binding of a command for menus and the palette, the user keymap first
+func commandBinding(id string) string {
+	label := ""
+	for _, b := range keymap {
+		if b.Command == id && (label == "" || b.User) {
+			label = b.Label
+		}
+	}
+	return label
+}
+
+// text listing of every active binding, opened in a buffer by help.keybindings
+func keybindingsText() string {
+	var sb strings.Builder
+	sb.WriteString("Active keybindings (defaults + " + configPath("keymap.json") + ")\n\n")
+
+	sorted := append([]*Binding(nil), keymap...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Command < sorted[j].Command })
+	for _, b := range sorted {
+		title := b.Command
+		if c, ok := commandsByID[b.Command]; ok {
+			title = c.Title
+		}
+		source := "default"
+		if b.User {
+			source = "user"
+		}
+		fmt.Fprintf(&sb, "%-20s %-34s %-22s %s\n", b.Label, title, b.Command, source)
+	}
+
+	if len(keymapErrors) > 0 {
+		sb.WriteString("\nErrors in keymap.json:\n")
+		for _, e := range keymapErrors {
+			sb.WriteString("  " + e + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func showKeybindings() {
+	loadStringIntoTextGrid(keybindingsText())
+	currentFile = "Untitled"
+	cursor.reset()
+	resetUndoRedoStacks()
+}
+
+func reloadKeymap() {
+	loadKeymap()
+	editorStatus = fmt.Sprintf("Keymap reloaded, %d bindings", len(keymap))
+	if len(keymapErrors) > 0 {
+		editorStatus += fmt.Sprintf(", %d errors (Help: Keyboard Shortcuts)", len(keymapErrors))
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestParseKeySequence(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []KeyChord
+	}{
+		{"Ctrl+Shift+P", []KeyChord{{Ctrl: true, Shift: true, Key: rl.KeyP}}},
+		{"ctrl+f3", []KeyChord{{Ctrl: true, Key: rl.KeyF3}}},
+		{"Alt+Left", []KeyChord{{Alt: true, Key: rl.KeyLeft}}},
+		{"Ctrl+K Ctrl+C", []KeyChord{{Ctrl: true, Key: rl.KeyK}, {Ctrl: true, Key: rl.KeyC}}},
+		{"Ctrl++", []KeyChord{{Ctrl: true, Shift: true, Key: rl.KeyEqual}}},
+		{"Ctrl+\\", []KeyChord{{Ctrl: true, Key: rl.KeyBackSlash}}},
+		{"Control+1", []KeyChord{{Ctrl: true, Key: rl.KeyOne}}},
+		{"PageDown", []KeyChord{{Key: rl.KeyPageDown}}},
+	}
+	for _, tt := range tests {
+		got, err := parseKeySequence(tt.in)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("parseKeySequence(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "Hyper+X", "Ctrl+Foo", "Ctrl+K Meta+C"} {
+		if _, err := parseKeySequence(in); err == nil {
+			t.Errorf("parseKeySequence(%q) did not fail", in)
+		}
+	}
+}
+
+func TestSequenceLabel(t *testing.T) {
+	for _, in := range []string{"Ctrl+Shift+P", "Ctrl+K Ctrl+C", "Alt+Left", "F3", "Ctrl+Shift+="} {
+		keys, err := parseKeySequence(in)
+		if err != nil {
+			t.Fatalf("parseKeySequence(%q): %v", in, err)
+		}
+		if got := sequenceLabel(keys); got != in {
+			t.Errorf("sequenceLabel(%q) = %q", in, got)
+		}
+	}
+}
+
+func TestLookupBinding(t *testing.T) {
+	defer func(saved []*Binding) { keymap = saved }(keymap)
+	bind := func(key, command string, user bool) {
+		keys, err := parseKeySequence(key)
+		if err != nil {
+			t.Fatalf("parseKeySequence(%q): %v", key, err)
+		}
+		keymap = append(keymap, &Binding{Keys: keys, Label: key, Command: command, User: user})
+	}
+	keymap = nil
+	bind("Ctrl+Z", "edit.undo", false)
+	bind("Ctrl+Shift+Z", "edit.redo", false)
+	bind("Alt+Left", "notes.back", false)
+	bind("F3", "search.findNext", false)
+	bind("Ctrl+K", "view.zen", false)
+	bind("Ctrl+K Ctrl+C", "edit.copy", false)
+	bind("Ctrl+B", "view.sidebar", false)
+	bind("Ctrl+B", "user.sidebar", true)
+	bind("Ctrl+B", "later.sidebar", false)
+
+	tests := []struct {
+		keys             string
+		command          string // "" for no complete binding
+		complete, prefix bool
+	}{
+		{"Ctrl+Z", "edit.undo", true, false},
+		{"Ctrl+Shift+Z", "edit.redo", true, false},
+		// modifiers have to be exact
+		{"Ctrl+Alt+Left", "", false, false},
+		{"Ctrl+F3", "", false, false},
+		{"Shift+Ctrl+Alt+Z", "", false, false},
+		// complete and the start of a longer sequence
+		{"Ctrl+K", "view.zen", true, true},
+		{"Ctrl+K Ctrl+C", "edit.copy", true, false},
+		{"Ctrl+K Ctrl+X", "", false, false},
+		// the user keymap wins over the defaults, even ones defined later
+		{"Ctrl+B", "user.sidebar", true, false},
+	}
+	for _, tt := range tests {
+		keys, err := parseKeySequence(tt.keys)
+		if err != nil {
+			t.Fatalf("parseKeySequence(%q): %v", tt.keys, err)
+		}
+		b, complete, prefix := lookupBinding(keys)
+		command := ""
+		if b != nil {
+			command = b.Command
+		}
+		if command != tt.command || complete != tt.complete || prefix != tt.prefix {
+			t.Errorf("lookupBinding(%s) = %q, %v, %v, want %q, %v, %v",
+				tt.keys, command, complete, prefix, tt.command, tt.complete, tt.prefix)
+		}
+	}
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Wiki links between notes. [[note name]] links a note by its file name, title or
+// path below the notebook root, [[dd-mm-yyyy]] the daily note of that day, text
+// after a | is the shown label ([[26-10-2026|monday]]). Links are drawn in the link
+// color in note buffers; Ctrl+click or Notes: Follow Link opens the note, or offers
+// to create it. Visited notes are kept in a history for Back/Forward (Alt+Left/Right)
+// and the Backlinks panel lists every note linking to the current one.
+
+type wikiLink struct {
+	start, end int // columns of the first [ and after the last ]
+	target     string
+}
+
+const maxNoteHistory = 100
+
+// the [[links]] of a line
+func findWikiLinks(line []byte) []wikiLink {
+	var links []wikiLink
+	for i := 0; i+1 < len(line); i++ {
+		if line[i] != '[' || line[i+1] != '[' {
+			continue
+		}
+		end := bytes.Index(line[i+2:], []byte("]]"))
+		if end == -1 {
+			break
+		}
+		inner := string(line[i+2 : i+2+end])
+		if strings.ContainsAny(inner, "[]") {
+			// "[[[x]]]", the link starts further right
+			continue
+		}
+		target, _, _ := strings.Cut(inner, "|")
+		if target = strings.TrimSpace(target); target == "" {
+			continue
+		}
+		links = append(links, wikiLink{start: i, end: i + 2 + end + 2, target: target})
+		i += 2 + end + 1
+	}
+	return links
+}
+
+// link targets of a note as link keys, for the index
+func noteLinkTargets(text string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.Contains(line, "[[") {
+			continue
+		}
+		for _, l := range findWikiLinks([]byte(line)) {
+			if key := linkKey(l.target); !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// compares link targets and note names: lower case, slashes, no .txt/.md
+func linkKey(s string) string {
+	s = strings.ToLower(strings.TrimSpace(filepath.ToSlash(s)))
+	if isNoteFile(s) {
+		s = strings.TrimSuffix(s, filepath.Ext(s))
+	}
+	return strings.Trim(s, "/")
+}
+
+// the names a note can be linked by, best first: path below the root, file name,
+// title, and for dated names (by the note pattern) the date as dd-mm-yyyy and the
+// title after " - " alone
+func noteLinkKeys(rel string, meta noteMeta) []string {
+	relKey := linkKey(rel)
+	base := relKey[strings.LastIndex(relKey, "/")+1:]
+	keys := []string{relKey, base}
+	if meta.Title != "" {
+		keys = append(keys, linkKey(meta.Title))
+	}
+	if t, ok := noteNameDate(base); ok {
+		keys = append(keys, t.Format("02-01-2006"))
+		if _, title, ok := strings.Cut(base, " - "); ok {
+			keys = append(keys, title)
+		}
+	}
+	return keys
+}
+
+// the note a link points to, the best kind of name wins, then the newest note
+func (ix *notesIndex) resolveLink(target string) (string, bool) {
+	key := linkKey(target)
+	best, bestRank := "", -1
+	for rel, note := range ix.Notes {
+		for rank, k := range noteLinkKeys(rel, note.Meta) {
+			if k != key {
+				continue
+			}
+			if bestRank == -1 || rank < bestRank || (rank == bestRank && note.ModTime > ix.Notes[best].ModTime) {
+				best, bestRank = rel, rank
+			}
+			break
+		}
+	}
+	return best, bestRank != -1
+}
+
+// index of the notebook of the current note, synced once
+func currentNoteIndex() *notesIndex {
+	ix := notebookIndexFor(currentFile)
+	if ix != nil && !ix.synced {
+		ix.sync()
+	}
+	return ix
+}
+
+// the link under a cell of the current buffer, only in notes
+func wikiLinkAt(x, y int) (wikiLink, bool) {
+	if !isNote(currentFile) {
+		return wikiLink{}, false
+	}
+	for _, l := range findWikiLinks(rowContent(y)) {
+		if x >= l.start && x < l.end {
+			return l, true
+		}
+	}
+	return wikiLink{}, false
+}
+
+// links of the visible lines, for drawing. Nil for files that are no notes
+func wikiLinksOfRows(rows []visualRow) map[int][]wikiLink {
+	if !isNote(currentFile) {
+		return nil
+	}
+	links := map[int][]wikiLink{}
+	for _, row := range rows {
+		if _, ok := links[row.line]; !ok {
+			links[row.line] = findWikiLinks(rowContent(row.line))
+		}
+	}
+	return links
+}
+
+func inWikiLink(links []wikiLink, x int) bool {
+	for _, l := range links {
+		if x >= l.start && x < l.end {
+			return true
+		}
+	}
+	return false
+}
+
+// ------------------------------------------------------------------------------------
+
+// Notes: Follow Link, the link at the cursor
+func followLinkAtCursor() {
+	l, ok := wikiLinkAt(cursor.x, cursor.y)
+	if !ok && cursor.x > 0 {
+		l, ok = wikiLinkAt(cursor.x-1, cursor.y)
+	}
+	if !ok {
+		editorStatus = "No [[link]] at the cursor"
+		return
+	}
+	followWikiLink(l.target)
+}
+
+// opens the linked note, a missing one can be created
+func followWikiLink(target string) {
+	ix := currentNoteIndex()
+	if ix == nil {
+		return
+	}
+	if rel, ok := ix.resolveLink(target); ok {
+		openNote(filepath.Join(ix.Root, filepath.FromSlash(rel)))
+		return
+	}
+	path, err := linkedNotePath(ix.Root, target)
+	if err != nil {
+		notifyWarning("Cannot create [[%s]]: %v", target, err)
+		return
+	}
+	message := fmt.Sprintf("There is no note [[%s]] yet. Create %s?", target, relSlash(ix.Root, path))
+	confirmDialog("Create Note", message, "Create", false, func() {
+		createLinkedNote(path)
+	})
+}
+
+// where a new note for a link goes: a date (and " - title") is the note of that day
+// by the note pattern, a name with a path goes below the root, other names next to
+// today's note. A path that leaves the notebook ([[../x]]) is an error
+func linkedNotePath(root, target string) (string, error) {
+	name := strings.TrimSpace(target)
+	var path string
+	switch t, ok := noteNameDate(name); {
+	case ok:
+		_, title, _ := strings.Cut(name, " - ")
+		path = notePath(root, t, title)
+	case strings.Contains(name, "/"):
+		path = filepath.Join(root, filepath.FromSlash(name))
+	default:
+		path = filepath.Join(filepath.Dir(notePath(root, time.Now(), "")), name)
+	}
+	if !isNoteFile(path) {
+		path += filepath.Ext(notePattern())
+	}
+	absRoot, err1 := filepath.Abs(root)
+	absPath, err2 := filepath.Abs(path)
+	if err1 != nil || err2 != nil || absPath == absRoot || !pathWithin(absPath, absRoot) {
+		return "", fmt.Errorf("%s is not inside the notebook", target)
+	}
+	return path, nil
+}
+
+func createLinkedNote(path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		notifyError("Could not create %s: %v", filepath.Dir(path), err)
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			notifyError("Could not create %s: %v", filepath.Base(path), err)
+			return
+		}
+		noteSaved(path)
+	}
+	openNote(path)
+	refreshFileLists("")
+}
+
+// opens a note, a note changed since it was saved is saved first
+func openNote(path string) {
+	saveModifiedNote()
+	openFileInEditor(path)
+}
+
+// saves the current buffer when it is a note with unsaved changes, before another
+// note replaces it
+func saveModifiedNote() {
+	if isNote(currentFile) && len(modifiedLines()) > 0 {
+		saveCurrentFile()
+	}
+}
+
+// ------------------------------------------------------------------------------------
+
+type noteVisit struct {
+	path string // absolute
+	x, y int
+}
+
+// visited notes, pos is the one shown (or last shown before a file that is no note)
+var noteHistory struct {
+	visits []noteVisit
+	pos    int
+	moving bool // set while Back/Forward loads a note
+}
+
+func sameFile(a, b string) bool {
+	absA, err1 := filepath.Abs(a)
+	absB, err2 := filepath.Abs(b)
+	return err1 == nil && err2 == nil && absA == absB
+}
+
+// called before a file is loaded, keeps the cursor of the note that is left
+func rememberNotePosition() {
+	h := &noteHistory
+	if h.pos < len(h.visits) && sameFile(h.visits[h.pos].path, currentFile) {
+		h.visits[h.pos].x, h.visits[h.pos].y = cursor.x, cursor.y
+	}
+}
+
+// called after a file was loaded, a note becomes the newest visit
+func noteOpened(path string) {
+	h := &noteHistory
+	if h.moving || !isNote(path) {
+		return
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	if h.pos < len(h.visits) && h.visits[h.pos].path == abs {
+		return
+	}
+	if len(h.visits) > 0 {
+		h.visits = h.visits[:h.pos+1]
+	}
+	h.visits = append(h.visits, noteVisit{path: abs})
+	if len(h.visits) > maxNoteHistory {
+		h.visits = h.visits[len(h.visits)-maxNoteHistory:]
+	}
+	h.pos = len(h.visits) - 1
+}
+
+func noteHistoryBack()    { moveInNoteHistory(-1) }
+func noteHistoryForward() { moveInNoteHistory(1) }
+
+func moveInNoteHistory(dir int) {
+	h := &noteHistory
+	if len(h.visits) == 0 {
+		editorStatus = "No visited notes"
+		return
+	}
+	i := h.pos + dir
+	if dir < 0 && !sameFile(h.visits[h.pos].path, currentFile) {
+		// back from a file that is no note returns to the last note
+		i = h.pos
+	}
+	if i < 0 || i >= len(h.visits) {
+		if dir < 0 {
+			editorStatus = "No older note"
+		} else {
+			editorStatus = "No newer note"
+		}
+		return
+	}
+
+	v := h.visits[i]
+	h.moving = true
+	openNote(v.path)
+	h.moving = false
+	if !sameFile(currentFile, v.path) {
+		return
+	}
+	h.pos = i
+	cursor.y = min(v.y, usedRows-1)
+	cursor.x = min(v.x, getRowWidth(cursor.y))
+	scrollToLineCentered(cursor.y)
+	ensureCursorVisible(cursor)
+}
+
+// ------------------------------------------------------------------------------------
+
+// the Backlinks panel, notes linking to the current note with the linking line
+type backlinksPanel struct {
+	noteResultList
+	name string
+}
+
+var backlinksModal = &backlinksPanel{}
+
+func openBacklinks() {
+	ix := currentNoteIndex()
+	if ix == nil {
+		editorStatus = "Backlinks are shown for notes"
+		return
+	}
+	b := backlinksModal
+	b.name = filepath.Base(currentFile)
+	b.results = ix.backlinks(relSlash(ix.Root, absPath(currentFile)))
+	b.selected = 0
+	b.scroll = 0
+	pushModal(b)
+}
+
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+// notes with a link to rel, newest first, with the line of the first such link
+func (ix *notesIndex) backlinks(rel string) []noteMatch {
+	note, ok := ix.Notes[rel]
+	if !ok {
+		return nil
+	}
+	keys := map[string]bool{}
+	for _, k := range noteLinkKeys(rel, note.Meta) {
+		keys[k] = true
+	}
+	var results []noteMatch
+	for _, from := range ix.notesLinking(keys) {
+		if from == rel {
+			continue
+		}
+		path := filepath.Join(ix.Root, filepath.FromSlash(from))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		m := noteMatch{Path: path, Rel: from, ModTime: ix.Notes[from].ModTime}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimRight(strings.ReplaceAll(line, "\t", "    "), "\r")
+			for _, l := range findWikiLinks([]byte(line)) {
+				if !keys[linkKey(l.target)] || m.Snippet != "" {
+					continue
+				}
+				// the resolved link may point to another note of the same name
+				if target, _ := ix.resolveLink(l.target); target != rel {
+					continue
+				}
+				m.Line, m.Snippet, m.Col, m.Len = i, line, l.start, l.end-l.start
+				for p := l.start; p < l.end; p++ {
+					m.Positions = append(m.Positions, p)
+				}
+			}
+		}
+		if m.Snippet != "" {
+			results = append(results, m)
+		}
+	}
+	return results
+}
+
+// notes with a link to one of keys, newest first
+func (ix *notesIndex) notesLinking(keys map[string]bool) []string {
+	var rels []string
+	for rel, note := range ix.Notes {
+		for _, l := range note.Links {
+			if keys[l] {
+				rels = append(rels, rel)
+				break
+			}
+		}
+	}
+	sortNotesNewestFirst(ix, rels)
+	return rels
+}
+
+func (b *backlinksPanel) drawModal(top bool) {
+	w := int32(min(900, windowWidth-40))
+	h := int32(min(520, windowHeight-editorTopPadding-editorBottomPadding-40))
+	x := int32(windowWidth)/2 - w/2
+	y := int32(editorTopPadding + 20)
+	listY := y + 52
+	visible := max(1, int(h-52-52)/notesSearchRowHeight)
+
+	if top {
+		switch {
+		case rl.IsKeyPressed(rl.KeyEscape):
+			closeModal(b)
+			return
+		case rl.IsKeyPressed(rl.KeyEnter):
+			if b.selected < len(b.results) {
+				closeModal(b)
+				openNoteMatch(b.results[b.selected])
+				return
+			}
+		default:
+			b.handleKeys(visible)
+		}
+	}
+
+	rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), ModernOverlay)
+	drawShadow(float32(x), float32(y), float32(w), float32(h), 4, 8)
+	rl.DrawRectangle(x, y, w, h, ModernMedium)
+	rl.DrawRectangleLines(x, y, w, h, ModernBorder)
+	rl.DrawRectangle(x, y, w, 44, ModernDark)
+	title := fmt.Sprintf("Backlinks to %s (%d)", b.name, len(b.results))
+	DrawText(clampText(title, int(w-40)/CHAR_IMAGE_WIDTH), int(x)+20, int(y)+16, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+
+	if len(b.results) == 0 {
+		DrawText("No note links to this one", int(x)+20, int(listY)+4, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+	} else if i := b.draw(x, listY, w, visible, top); i != -1 {
+		closeModal(b)
+		openNoteMatch(b.results[i])
+		return
+	}
+
+	if DrawModernButton("Close", x+w-100, y+h-44, 80, 32, ModernText, ModernDanger, ModernLight, ModernMedium, true) && top {
+		closeModal(b)
+	}
+}
@@ -16,6 +16,15 @@ func main() {
 		log.Println(http.ListenAndServe("localhost:6060", nil))
 	}()
 
+	// before the file, its highlighting and notebook depend on the settings
+	loadSettings()
+	loadLanguages()
+	loadThemes()
+	registerDefaultCommands()
+	registerThemeCommands()
+	registerLineNumberCommands()
+	loadKeymap()
+
 	var file string
 	if len(os.Args) > 1 {
 		file = os.Args[1]
@@ -31,68 +40,36 @@ func main() {
 	defer rl.CloseWindow()
 	rl.SetTargetFPS(60)
 
+	// Esc closes dialogs and the palette, quitting is Ctrl+Q
+	rl.SetExitKey(rl.KeyNull)
+
+	initClipboard()
 	editorClipboard = rl.GetClipboardText()
 	var clipboardMutex sync.Mutex
 
 	for !rl.WindowShouldClose() {
+		checkThemeReload()
+		projectIndex.check()
 		if rl.IsWindowResized() {
 			windowHeight = rl.GetScreenHeight()
 			windowWidth = rl.GetScreenWidth()
 		}
-		if ui.ModalOpen == "" {
+		layoutPanes()
+		if handleToastInput() {
+			// the click dismissed a toast
+		} else if !modalOpen() && handleMenuInput() {
+			// an open menu has the keyboard and the mouse
+		} else if !modalOpen() && ui.ShowFilePicker {
+			// the picker has the keyboard while it is open
+			handleFilePickerInput(ui)
+		} else if !modalOpen() {
 			handleEditorInput(cursor)
 
-			if ui.ModalOpen == "" {
-				// handle mouse click to reposition cursor
-				if rl.IsMouseButtonPressed(rl.MouseLeftButton) {
-					// start selection
-					mouseX := rl.GetMouseX()
-					mouseY := rl.GetMouseY()
-
-					gridX := (int(mouseX) - editorXPadding) / CHAR_IMAGE_WIDTH
-					gridY := (int(mouseY) - editorTopPadding) / CHAR_IMAGE_HEIGHT
-
-					gridX += scrollOffsetX
-					gridY += scrollOffsetY
-
-					if gridX >= 0 && gridX < visibleCols && gridY >= 0 && gridY < visibleRows {
-						cursor.MoveToClick(gridX, gridY)
-
-						selection.Active = true
-						selection.StartX = gridX
-						selection.StartY = gridY
-						selection.EndX = gridX
-						selection.EndY = gridY
-					}
-				}
-
-				if rl.IsMouseButtonDown(rl.MouseLeftButton) && selection.Active {
-					mouseX := rl.GetMouseX()
-					mouseY := rl.GetMouseY()
-
-					gridX := (int(mouseX) - editorXPadding) / CHAR_IMAGE_WIDTH
-					gridY := (int(mouseY) - editorTopPadding) / CHAR_IMAGE_HEIGHT
-
-					gridX += scrollOffsetX
-					gridY += scrollOffsetY
-
-					if gridX >= 0 && gridX < visibleCols && gridY >= 0 && gridY < visibleRows {
-						selection.EndX = gridX
-						selection.EndY = gridY
-					}
-				}
-
-				if rl.IsMouseButtonReleased(rl.MouseLeftButton) && selection.Active {
-					if selection.StartX == selection.EndX && selection.StartY == selection.EndY {
-						selection.Active = false // No drag = no selection
-						// ensureCursorVisible(cursor)
-						clampCursor()
-						continue
-					}
-					cursor.x = selection.EndX
-					cursor.y = selection.EndY
-					clampCursor()
-					// ensureCursorVisible(cursor)
+			if !modalOpen() {
+				handleSidebarInput()
+				if !handlePaneInput() {
+					handleMinimapInput()
+					handleTextMouse()
 				}
 			}
 
@@ -101,101 +78,135 @@ func main() {
 		rl.BeginDrawing()
 		rl.ClearBackground(ModernDarkBg)
 
-		if ui.ModalOpen == "" {
-			visibleRows := getVisibleRows()
-			visibleCols := getVisibleCols()
-
-			startY := scrollOffsetY
-			endY := scrollOffsetY + visibleRows
-			if endY > usedRows {
-				endY = usedRows
-			}
-
-			startX := scrollOffsetX
-			endX := scrollOffsetX + visibleCols
-			maxContentWidth := getMaxContentWidth()
-			if endX > maxContentWidth {
-				endX = maxContentWidth
-			}
-
-			// line highlight
-			rl.DrawRectangle(
-				int32(editorXPadding),
-				int32((cursor.y-scrollOffsetY)*CHAR_IMAGE_HEIGHT+editorTopPadding+editorYPadding),
-				int32(windowWidth-20), CHAR_IMAGE_HEIGHT, rl.NewColor(80, 82, 122, 100))
-
-			// render only visible characters
-			for y := startY; y < endY; y++ {
-				for x := startX; x < endX; x++ {
-					screenX := ((x - scrollOffsetX) * CHAR_IMAGE_WIDTH) + editorXPadding
-					screenY := ((y - scrollOffsetY) * CHAR_IMAGE_HEIGHT) + editorTopPadding
-
-					// dodge 0 and \n for selection aswell
-					if textGrid[y][x] == 0 || textGrid[y][x] == '\n' {
-						continue
-					}
-					// draw selection
-					if isCellSelected(x, y) {
-						rl.DrawRectangle(int32(screenX), int32(screenY)+int32(editorYPadding), CHAR_IMAGE_WIDTH, CHAR_IMAGE_HEIGHT, ModernLight)
-					}
-
-					char := textGrid[y][x]
-					if char >= 32 && char <= 126 {
-						DrawCharacter(char,
-							((x-scrollOffsetX)*CHAR_IMAGE_WIDTH)+editorXPadding,
-							((y-scrollOffsetY)*CHAR_IMAGE_HEIGHT)+editorTopPadding+editorYPadding,
-							rl.DrawPixel,
-							"white")
-					} else if char == 0 || char == '\n' {
-						continue
-					} else {
-						// ' ' space char
-						DrawCharacter(1,
-							((x-scrollOffsetX)*CHAR_IMAGE_WIDTH)+editorXPadding,
-							((y-scrollOffsetY)*CHAR_IMAGE_HEIGHT)+editorTopPadding+editorYPadding,
-							rl.DrawPixel,
-							"white")
-						continue
-					}
-				}
-			}
-
-			// render cursor only if it's visible
-			if cursor.y >= scrollOffsetY && cursor.y < scrollOffsetY+visibleRows &&
-				cursor.x >= scrollOffsetX && cursor.x < scrollOffsetX+visibleCols {
-				DrawCharacter(4,
-					((cursor.x-scrollOffsetX)*CHAR_IMAGE_WIDTH)+editorXPadding,
-					((cursor.y-scrollOffsetY)*CHAR_IMAGE_HEIGHT)+editorTopPadding+editorYPadding,
-					rl.DrawPixel,
-					"red")
-			}
-
-			// draw scroll indicators
-			drawScrollIndicators()
+		// every pane is drawn with its own state, the focused one is active again after.
+		// Open dialogs are drawn on top, dimming the editor
+		for _, p := range panes() {
+			p.activate()
+			drawPane()
 		}
+		focusedPane.activate()
+		drawPaneDividers()
+		drawSidebar()
+		drawTagCompletion()
 
 		DrawMenuBar(ui)
 		DrawStatusBar(*cursor)
-		// fmt.Println("clipboard:", editorClipboard)
 		clipboardMutex.Lock()
 		tmp := rl.GetClipboardText()
-		// fmt.Println("tmp clipboard: ", tmp)
 		if tmp != editorClipboard && tmp != "" {
 			editorClipboard = tmp
-			fmt.Println("Clipboard updated from system (window focused):", editorClipboard)
 		}
 		clipboardMutex.Unlock()
 
+		mouseBlocked = modalOpen()
 		if ui.ShowNotesPanel {
 			DrawNotesPanel(ui)
 		}
 		if ui.ShowFilePicker {
 			DrawFilePickerPanel(ui)
 		}
-		if ui.ModalOpen != "" {
+		mouseBlocked = false
+		if modalOpen() {
 			DrawModal(ui)
 		}
+		drawToasts()
 
 		rl.EndDrawing()
 	}
 }
+
+// text, gutter, minimap and scroll bars of the active pane
+func drawPane() {
+	rows := displayRows()
+
+	// tokenize everything up to the last visible line
+	if len(rows) > 0 {
+		highlighter.update(rows[len(rows)-1].line + 1)
+	}
+
+	// line highlight
+	for i, row := range rows {
+		if row.line == cursor.y {
+			rl.DrawRectangle(
+				int32(textAreaX()),
+				int32(i*CHAR_IMAGE_HEIGHT+textAreaY()+editorYPadding),
+				int32(textAreaRight()-textAreaX()), CHAR_IMAGE_HEIGHT, EditorCurrentLineColor)
+		}
+	}
+
+	// render only visible characters
+	ws := settings.Whitespace
+	links := wikiLinksOfRows(rows)
+	for i, row := range rows {
+		y := row.line
+		kinds := highlighter.lineKinds(y)
+		screenY := (i * CHAR_IMAGE_HEIGHT) + textAreaY()
+		trailingStart := trailingWhitespaceStart(y)
+		for x := row.start; x < row.end; x++ {
+			screenX := ((x - row.start) * CHAR_IMAGE_WIDTH) + textAreaX()
+
+			// dodge 0 and \n for selection aswell
+			if textGrid[y][x] == 0 {
+				continue
+			}
+			if textGrid[y][x] == '\n' {
+				if ws.Show && ws.LineEnds {
+					drawLineEndMarker(screenX, screenY+editorYPadding)
+				}
+				continue
+			}
+			// draw selection
+			if isCellSelected(x, y) {
+				rl.DrawRectangle(int32(screenX), int32(screenY)+int32(editorYPadding), CHAR_IMAGE_WIDTH, CHAR_IMAGE_HEIGHT, EditorSelectionColor)
+			} else if ws.Show && ws.Trailing && x >= trailingStart {
+				drawTrailingWhitespace(screenX, screenY+editorYPadding)
+			}
+
+			char := textGrid[y][x]
+			if char == ' ' {
+				if ws.Show && ws.Spaces {
+					drawSpaceMarker(screenX, screenY+editorYPadding)
+				}
+			} else if char == '\t' {
+				if ws.Show && ws.Tabs {
+					drawTabMarker(screenX, screenY+editorYPadding)
+				}
+			} else if char >= 32 && char <= 126 {
+				color := colorForKinds(kinds, x)
+				if inWikiLink(links[y], x) {
+					color = "syntax.link"
+				}
+				DrawCharacter(char,
+					screenX,
+					screenY+editorYPadding,
+					rl.DrawPixel,
+					color)
+			} else if char == 0 || char == '\n' {
+				continue
+			} else {
+				// control / non ascii bytes
+				drawControlChar(char, screenX, screenY+editorYPadding)
+				continue
+			}
+		}
+	}
+
+	// render cursor only if it's visible
+	for i, row := range rows {
+		if row.line == cursor.y && row.holdsColumn(cursor.x) {
+			DrawCharacter(4,
+				((cursor.x-row.start)*CHAR_IMAGE_WIDTH)+textAreaX(),
+				(i*CHAR_IMAGE_HEIGHT)+textAreaY()+editorYPadding,
+				rl.DrawPixel,
+				"editor.cursor")
+			break
+		}
+	}
+
+	drawDropMarker(rows)
+	drawGutter(rows)
+	drawMinimap(rows)
+
+	// draw scroll indicators
+	drawScrollIndicators()
+}
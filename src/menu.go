@@ -0,0 +1,540 @@
+package main
+
+import (
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Menu bar. The menus are built again every frame from the current state, so
+// checkmarks and disabled items are always up to date. Items run commands and show
+// their keybinding. A "&" in a label marks the mnemonic: Alt+letter (or F10) opens
+// a menu, inside an open menu the letter alone picks an item. Up/Down move, Right
+// opens a submenu or the next menu, Left goes back, Enter runs, Esc closes. While a
+// menu is open it has the keyboard and the mouse.
+
+const menuItemHeight = 24
+const menuSeparatorHeight = 9
+
+type MenuItem struct {
+	Label     string
+	Command   string
+	Run       func() // instead of Command, for actions on something clicked
+	Shortcut  string // shown for Run items, command items show their keybinding
+	Submenu   []MenuItem
+	Separator bool
+	Checked   bool
+	Disabled  bool
+}
+
+type Menu struct {
+	Label string
+	Items []MenuItem
+}
+
+// an open dropdown on screen
+type menuPanel struct {
+	items []MenuItem
+	x, y  int
+	w, h  int
+}
+
+func menuSeparator() MenuItem {
+	return MenuItem{Separator: true}
+}
+
+func menuBar() []Menu {
+	var lineNumbers []MenuItem
+	for _, mode := range lineNumberModes {
+		lineNumbers = append(lineNumbers, MenuItem{
+			Label:   "&" + strings.ToUpper(mode[:1]) + mode[1:],
+			Command: "view.lineNumbers." + mode,
+			Checked: settings.LineNumbers == mode || (mode == "off" && settings.LineNumbers == ""),
+		})
+	}
+	var themeItems []MenuItem
+	for _, name := range themeNames() {
+		themeItems = append(themeItems, MenuItem{
+			Label:   name,
+			Command: "view.theme." + strings.ToLower(name),
+			Checked: activeTheme != nil && activeTheme.Name == name,
+		})
+	}
+	singlePane := len(panes()) == 1
+
+	return []Menu{
+		{"&File", []MenuItem{
+			{Label: "&New", Command: "file.new"},
+			{Label: "&Open...", Command: "file.open"},
+			{Label: "Q&uick Open...", Command: "file.quickOpen"},
+			{Label: "&Browse Files", Command: "file.openPicker", Checked: ui.ShowFilePicker},
+			menuSeparator(),
+			{Label: "&Save", Command: "file.save"},
+			{Label: "Save &As...", Command: "file.saveAs"},
+			menuSeparator(),
+			{Label: "&Rebuild File Index", Command: "file.reindex"},
+			menuSeparator(),
+			{Label: "&Quit", Command: "app.quit"},
+		}},
+		{"&Edit", []MenuItem{
+			{Label: "&Undo", Command: "edit.undo", Disabled: len(undoStack) == 0},
+			{Label: "&Redo", Command: "edit.redo", Disabled: len(redoStack) == 0},
+			menuSeparator(),
+			{Label: "Cu&t", Command: "edit.cut", Disabled: !selection.Active},
+			{Label: "&Copy", Command: "edit.copy", Disabled: !selection.Active},
+			{Label: "&Paste", Command: "edit.paste", Disabled: editorClipboard == ""},
+			menuSeparator(),
+			{Label: "&Find Word / Selection", Command: "search.find"},
+			{Label: "Find &Next", Command: "search.findNext", Disabled: searchQuery == ""},
+		}},
+		{"&Selection", []MenuItem{
+			{Label: "Select &All", Command: "edit.selectAll"},
+			{Label: "Select &Line", Command: "selection.line"},
+			{Label: "Select &Word", Command: "selection.word"},
+			menuSeparator(),
+			{Label: "&Clear Selection", Command: "selection.clear", Disabled: !selection.Active},
+		}},
+		{"&View", []MenuItem{
+			{Label: "Command &Palette...", Command: "view.commandPalette"},
+			menuSeparator(),
+			{Label: "&Line Numbers", Submenu: lineNumbers},
+			{Label: "Word &Wrap", Command: "view.wordWrap", Checked: settings.WordWrap},
+			{Label: "W&hitespace", Command: "view.whitespace", Checked: settings.Whitespace.Show},
+			{Label: "&Minimap", Command: "view.minimap", Checked: settings.Minimap},
+			{Label: "&Sidebar", Command: "view.sidebar", Checked: settings.Sidebar},
+			{Label: "M&essages...", Command: "view.messages"},
+			menuSeparator(),
+			{Label: "Split &Right", Command: "view.splitRight"},
+			{Label: "Split &Down", Command: "view.splitDown"},
+			{Label: "&Close Pane", Command: "view.closePane", Disabled: singlePane},
+			menuSeparator(),
+			{Label: "&Theme", Submenu: themeItems, Disabled: len(themeItems) == 0},
+		}},
+		{"&Go", []MenuItem{
+			{Label: "Go to &File...", Command: "file.quickOpen"},
+			menuSeparator(),
+			{Label: "Document &Start", Command: "go.documentStart"},
+			{Label: "Document &End", Command: "go.documentEnd"},
+			menuSeparator(),
+			{Label: "&Next Pane", Command: "view.focusNextPane", Disabled: singlePane},
+			{Label: "&Previous Pane", Command: "view.focusPreviousPane", Disabled: singlePane},
+		}},
+		{"&Notes", []MenuItem{
+			{Label: "&Show Notes", Command: "notes.panel", Checked: ui.ShowNotesPanel},
+			{Label: "&Create Note...", Command: "notes.create"},
+			{Label: "&Delete Note", Command: "notes.delete", Disabled: !isNote(currentFile)},
+			{Label: "Se&arch Notes...", Command: "notes.search"},
+			{Label: "Browse &Tags", Command: "notes.tags"},
+			menuSeparator(),
+			{Label: "&Follow Link", Command: "notes.followLink", Disabled: !isNote(currentFile)},
+			{Label: "Back&links...", Command: "notes.backlinks", Disabled: !isNote(currentFile)},
+			{Label: "Bac&k", Command: "notes.back"},
+			{Label: "For&ward", Command: "notes.forward"},
+			menuSeparator(),
+			{Label: "Note&book", Submenu: notebookItems()},
+		}},
+		{"&Help", []MenuItem{
+			{Label: "&Keyboard Shortcuts", Command: "help.keybindings"},
+			{Label: "&Reload Keymap", Command: "help.reloadKeymap"},
+		}},
+	}
+}
+
+// label without the "&", the lower case mnemonic and its index, -1 when there is none
+func menuLabel(label string) (string, byte, int) {
+	i := strings.IndexByte(label, '&')
+	if i == -1 || i == len(label)-1 {
+		return label, 0, -1
+	}
+	text := label[:i] + label[i+1:]
+	mnemonic := label[i+1]
+	if mnemonic >= 'A' && mnemonic <= 'Z' {
+		mnemonic += 'a' - 'A'
+	}
+	return text, mnemonic, i
+}
+
+func (item MenuItem) selectable() bool {
+	return !item.Separator && !item.Disabled
+}
+
+func (item MenuItem) shortcut() string {
+	if item.Command != "" {
+		return commandBinding(item.Command)
+	}
+	return item.Shortcut
+}
+
+// x and width of every title in the bar
+func menuTitleRects(menus []Menu) ([]int, []int) {
+	xs := make([]int, len(menus))
+	ws := make([]int, len(menus))
+	x := 10
+	for i, m := range menus {
+		text, _, _ := menuLabel(m.Label)
+		xs[i] = x
+		ws[i] = len(text)*CHAR_IMAGE_WIDTH + 20
+		x += ws[i] + 4
+	}
+	return xs, ws
+}
+
+func menuTitleAt(menus []Menu, mouseX, mouseY int) int {
+	if mouseY < 0 || mouseY >= editorTopPadding {
+		return -1
+	}
+	xs, ws := menuTitleRects(menus)
+	for i := range menus {
+		if mouseX >= xs[i] && mouseX < xs[i]+ws[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+func activeMenuIndex(menus []Menu) int {
+	for i, m := range menus {
+		if text, _, _ := menuLabel(m.Label); text == ui.ActiveMenu {
+			return i
+		}
+	}
+	return -1
+}
+
+func newMenuPanel(items []MenuItem, x, y int) menuPanel {
+	p := menuPanel{items: items, x: x, y: y, w: 140, h: 8}
+	for _, item := range items {
+		if item.Separator {
+			p.h += menuSeparatorHeight
+			continue
+		}
+		p.h += menuItemHeight
+		text, _, _ := menuLabel(item.Label)
+		w := 24 + (len(text)+3)*CHAR_IMAGE_WIDTH + 16
+		if binding := item.shortcut(); binding != "" {
+			w += len(binding) * CHAR_IMAGE_WIDTH
+		}
+		p.w = max(p.w, w)
+	}
+	if p.x+p.w > windowWidth {
+		p.x = max(0, windowWidth-p.w)
+	}
+	return p
+}
+
+func (p menuPanel) itemY(index int) int {
+	y := p.y + 4
+	for _, item := range p.items[:index] {
+		if item.Separator {
+			y += menuSeparatorHeight
+		} else {
+			y += menuItemHeight
+		}
+	}
+	return y
+}
+
+func (p menuPanel) itemAt(mouseX, mouseY int) int {
+	if mouseX < p.x || mouseX >= p.x+p.w {
+		return -1
+	}
+	for i, item := range p.items {
+		y := p.itemY(i)
+		h := menuItemHeight
+		if item.Separator {
+			h = menuSeparatorHeight
+		}
+		if mouseY >= y && mouseY < y+h {
+			return i
+		}
+	}
+	return -1
+}
+
+// the dropdown of the open menu and the submenus opened along ui.MenuPath
+func openMenuPanels(menus []Menu) []menuPanel {
+	index := activeMenuIndex(menus)
+	if index == -1 {
+		return nil
+	}
+	xs, _ := menuTitleRects(menus)
+	panels := []menuPanel{newMenuPanel(menus[index].Items, xs[index], editorTopPadding)}
+	for level := 0; level+1 < len(ui.MenuPath); level++ {
+		p := panels[level]
+		i := ui.MenuPath[level]
+		if i < 0 || i >= len(p.items) || p.items[i].Submenu == nil {
+			break
+		}
+		sub := newMenuPanel(p.items[i].Submenu, p.x+p.w-2, p.itemY(i)-4)
+		if p.x+p.w+sub.w > windowWidth {
+			// no room on the right, open on the left of the parent
+			sub.x = max(0, p.x-sub.w+2)
+		}
+		panels = append(panels, sub)
+	}
+	return panels
+}
+
+func openMenu(menus []Menu, index int, keyboard bool) {
+	ui.ActiveMenu, _, _ = menuLabel(menus[index].Label)
+	ui.MenuPath = []int{-1}
+	if keyboard {
+		ui.MenuPath[0] = nextMenuItem(menus[index].Items, -1, 1)
+	}
+}
+
+func closeMenu() {
+	ui.ActiveMenu = ""
+	ui.MenuPath = nil
+}
+
+// next selectable item after from in direction dir, wrapping around
+func nextMenuItem(items []MenuItem, from, dir int) int {
+	n := len(items)
+	for step := 1; step <= n; step++ {
+		i := ((from+dir*step)%n + n) % n
+		if items[i].selectable() {
+			return i
+		}
+	}
+	return from
+}
+
+// runs the item or opens its submenu, level is the panel it is in
+func activateMenuItem(item MenuItem, level int, keyboard bool) {
+	if !item.selectable() {
+		return
+	}
+	if item.Submenu != nil {
+		ui.MenuPath = ui.MenuPath[:level+1]
+		first := -1
+		if keyboard {
+			first = nextMenuItem(item.Submenu, -1, 1)
+		}
+		ui.MenuPath = append(ui.MenuPath, first)
+		return
+	}
+	closeMenu()
+	if item.Run != nil {
+		item.Run()
+	} else {
+		runCommand(item.Command)
+	}
+}
+
+func letterKey(letter byte) int32 {
+	return int32(rl.KeyA) + int32(letter-'a')
+}
+
+// true when the menu took the input this frame, the editor then gets none
+func handleMenuInput() bool {
+	menus := menuBar()
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+	pressed := rl.IsMouseButtonPressed(rl.MouseLeftButton)
+	title := menuTitleAt(menus, mouseX, mouseY)
+
+	if ui.ActiveMenu == "" {
+		if pressed && title != -1 {
+			openMenu(menus, title, false)
+			return true
+		}
+		if rl.IsKeyPressed(rl.KeyF10) {
+			openMenu(menus, 0, true)
+			return true
+		}
+		if altDown() && !ctrlDown() {
+			for i, m := range menus {
+				_, mnemonic, _ := menuLabel(m.Label)
+				if mnemonic < 'a' || mnemonic > 'z' || !rl.IsKeyPressed(letterKey(mnemonic)) {
+					continue
+				}
+				// a keybinding on the same Alt+letter wins
+				if _, complete, _ := lookupBinding([]KeyChord{{Alt: true, Key: letterKey(mnemonic)}}); complete {
+					return false
+				}
+				openMenu(menus, i, true)
+				return true
+			}
+		}
+		return false
+	}
+
+	index := activeMenuIndex(menus)
+	if index == -1 {
+		closeMenu()
+		return false
+	}
+	panels := openMenuPanels(menus)
+	level := len(panels) - 1
+	if len(ui.MenuPath) > len(panels) {
+		ui.MenuPath = ui.MenuPath[:len(panels)]
+	}
+	deepest := panels[level]
+	current := ui.MenuPath[level]
+
+	// mouse: moving over a title switches menus, over an item highlights it
+	moved := rl.GetMouseDelta().X != 0 || rl.GetMouseDelta().Y != 0
+	if title != -1 && (pressed || moved) {
+		if pressed && title == index {
+			closeMenu()
+		} else if title != index {
+			openMenu(menus, title, false)
+		}
+		return true
+	}
+	for l := len(panels) - 1; l >= 0; l-- {
+		p := panels[l]
+		if mouseX < p.x || mouseX >= p.x+p.w || mouseY < p.y || mouseY >= p.y+p.h {
+			continue
+		}
+		i := p.itemAt(mouseX, mouseY)
+		if i == -1 {
+			return true
+		}
+		item := p.items[i]
+		if moved && item.selectable() {
+			ui.MenuPath = append(ui.MenuPath[:l], i)
+			if item.Submenu != nil {
+				ui.MenuPath = append(ui.MenuPath, -1)
+			}
+		}
+		if pressed {
+			activateMenuItem(item, l, false)
+		}
+		return true
+	}
+	if pressed || rl.IsMouseButtonPressed(rl.MouseRightButton) {
+		// click outside the menus
+		closeMenu()
+		return true
+	}
+
+	switch {
+	case rl.IsKeyPressed(rl.KeyEscape):
+		if level > 0 {
+			ui.MenuPath = ui.MenuPath[:level]
+		} else {
+			closeMenu()
+		}
+	case rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown):
+		ui.MenuPath[level] = nextMenuItem(deepest.items, current, 1)
+	case rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp):
+		if current == -1 {
+			current = len(deepest.items)
+		}
+		ui.MenuPath[level] = nextMenuItem(deepest.items, current, -1)
+	case rl.IsKeyPressed(rl.KeyRight):
+		if current != -1 && deepest.items[current].Submenu != nil {
+			activateMenuItem(deepest.items[current], level, true)
+		} else {
+			openMenu(menus, (index+1)%len(menus), true)
+		}
+	case rl.IsKeyPressed(rl.KeyLeft):
+		if level > 0 {
+			ui.MenuPath = ui.MenuPath[:level]
+		} else {
+			openMenu(menus, (index+len(menus)-1)%len(menus), true)
+		}
+	case rl.IsKeyPressed(rl.KeyEnter) || rl.IsKeyPressed(rl.KeySpace):
+		if current != -1 {
+			activateMenuItem(deepest.items[current], level, true)
+		}
+	default:
+		// mnemonics of the deepest open panel, with or without Alt
+		for _, item := range deepest.items {
+			_, mnemonic, _ := menuLabel(item.Label)
+			if mnemonic >= 'a' && mnemonic <= 'z' && rl.IsKeyPressed(letterKey(mnemonic)) {
+				activateMenuItem(item, level, true)
+				break
+			}
+		}
+	}
+	return true
+}
+
+// ------------------------------------------------------------------------------------
+
+func DrawMenuBar(ui *UIState) {
+	menuHeight := editorTopPadding
+	menus := menuBar()
+
+	rl.DrawRectangle(0, 0, int32(windowWidth), int32(menuHeight), ModernDark)
+	rl.DrawRectangle(0, int32(menuHeight-2), int32(windowWidth), 2, ModernAccent)
+
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+	hover := -1
+	if !modalOpen() {
+		hover = menuTitleAt(menus, mouseX, mouseY)
+	}
+	active := activeMenuIndex(menus)
+	xs, ws := menuTitleRects(menus)
+	for i, m := range menus {
+		switch {
+		case i == active:
+			rl.DrawRectangle(int32(xs[i]), 6, int32(ws[i]), int32(menuHeight-12), ModernAccent)
+		case i == hover:
+			rl.DrawRectangle(int32(xs[i]), 6, int32(ws[i]), int32(menuHeight-12), ModernLight)
+		}
+		drawMenuLabel(m.Label, xs[i]+10, 6+(menuHeight-12)/2-5, "ui.text")
+	}
+
+	panels := openMenuPanels(menus)
+	for level, p := range panels {
+		selected := -1
+		if level < len(ui.MenuPath) {
+			selected = ui.MenuPath[level]
+		}
+		drawMenuPanel(p, selected)
+	}
+}
+
+func drawMenuPanel(p menuPanel, selected int) {
+	drawShadow(float32(p.x), float32(p.y), float32(p.w), float32(p.h), 2, 4)
+	rl.DrawRectangle(int32(p.x), int32(p.y), int32(p.w), int32(p.h), ModernMedium)
+	rl.DrawRectangleLines(int32(p.x), int32(p.y), int32(p.w), int32(p.h), ModernBorder)
+
+	for i, item := range p.items {
+		y := p.itemY(i)
+		if item.Separator {
+			rl.DrawRectangle(int32(p.x+8), int32(y+menuSeparatorHeight/2), int32(p.w-16), 1, ModernBorder)
+			continue
+		}
+		if i == selected && !item.Disabled {
+			rl.DrawRectangle(int32(p.x+4), int32(y), int32(p.w-8), menuItemHeight, ModernLight)
+		}
+
+		color := "ui.text"
+		if item.Disabled {
+			color = "ui.textDim"
+		}
+		textY := y + menuItemHeight/2 - 5
+		if item.Checked {
+			drawCheckmark(p.x+10, y+menuItemHeight/2)
+		}
+		drawMenuLabel(item.Label, p.x+24, textY, color)
+
+		right := p.x + p.w - 12
+		if item.Submenu != nil {
+			DrawText(">", right-CHAR_IMAGE_WIDTH, textY, CHAR_IMAGE_WIDTH, rl.DrawPixel, color)
+		} else if binding := item.shortcut(); binding != "" {
+			DrawText(binding, right-len(binding)*CHAR_IMAGE_WIDTH, textY, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		}
+	}
+}
+
+// label text with the mnemonic letter underlined
+func drawMenuLabel(label string, x, y int, color string) {
+	text, _, index := menuLabel(label)
+	DrawText(text, x, y, CHAR_IMAGE_WIDTH, rl.DrawPixel, color)
+	if index != -1 {
+		rgb, _ := getRGBForColor(color)
+		rl.DrawRectangle(int32(x+index*CHAR_IMAGE_WIDTH), int32(y+12), CHAR_IMAGE_WIDTH-1, 1, rl.NewColor(rgb[0], rgb[1], rgb[2], 255))
+	}
+}
+
+// small tick centered on the left edge of x, y
+func drawCheckmark(x, y int) {
+	fx, fy := float32(x), float32(y)
+	rl.DrawLineEx(rl.NewVector2(fx, fy), rl.NewVector2(fx+3, fy+3), 2, ModernAccent)
+	rl.DrawLineEx(rl.NewVector2(fx+3, fy+3), rl.NewVector2(fx+9, fy-4), 2, ModernAccent)
+}
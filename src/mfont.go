@@ -16,6 +16,7 @@ var colorMap = map[string][3]byte{
 	"yellow": {0xFF, 0xFF, 0x00},
 	"cyan":   {0x00, 0xFF, 0xFF},
 	"white":  {0xFF, 0xFF, 0xFF},
+	"gray":   {0x80, 0x84, 0x9C},
 }
 
 func calculateLuminance(r, g, b byte) float64 {
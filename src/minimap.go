@@ -0,0 +1,212 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// The minimap is a column on the right with the whole buffer drawn one pixel per
+// character. Small files get two pixel rows per line, big ones are squeezed so the
+// whole document always fits, the part currently on screen is shaded.
+
+var minimapDragging bool
+
+// hashes of the lines as they were when the file was loaded / saved, used to mark
+// modified lines
+var savedLines = map[uint64]bool{}
+
+type modifiedLinesCache struct {
+	version int
+	lines   []int
+}
+
+var modifiedCache modifiedLinesCache
+
+func markBufferSaved() {
+	savedLines = map[uint64]bool{}
+	for y := 0; y < usedRows; y++ {
+		savedLines[hashBytes(rowContent(y))] = true
+	}
+	modifiedCache.version = -1
+}
+
+// lines whose content was not in the file when it was last loaded or saved
+func modifiedLines() []int {
+	if modifiedCache.version == bufferVersion {
+		return modifiedCache.lines
+	}
+	modifiedCache.version = bufferVersion
+	modifiedCache.lines = modifiedCache.lines[:0]
+	for y := 0; y < usedRows; y++ {
+		if !savedLines[hashBytes(rowContent(y))] {
+			modifiedCache.lines = append(modifiedCache.lines, y)
+		}
+	}
+	return modifiedCache.lines
+}
+
+func toggleMinimap() {
+	settings.Minimap = !settings.Minimap
+	minimapDragging = false
+	ensureCursorVisible(cursor)
+}
+
+// space the minimap takes from the text area, 0 when it is hidden
+func minimapWidth() int {
+	if !settings.Minimap {
+		return 0
+	}
+	return minimapColumns() + 6
+}
+
+func minimapColumns() int {
+	if settings.MinimapWidth <= 0 {
+		return 80
+	}
+	return settings.MinimapWidth
+}
+
+func minimapRect() (int, int, int, int) {
+	w := minimapColumns()
+	x := activePane.x + activePane.w - 12 - w
+	y := activePane.y
+	h := activePane.h
+	return x, y, w, h
+}
+
+// pixel rows per line, below 1 when the document is squeezed
+func minimapLineHeight() float32 {
+	_, _, _, h := minimapRect()
+	if usedRows*2 <= h {
+		return 2
+	}
+	return float32(h) / float32(usedRows)
+}
+
+func minimapLineY(line int) int {
+	_, y, _, _ := minimapRect()
+	return y + int(float32(line)*minimapLineHeight())
+}
+
+func minimapLineAt(screenY int) int {
+	_, y, _, _ := minimapRect()
+	line := int(float32(screenY-y) / minimapLineHeight())
+	if line < 0 {
+		line = 0
+	}
+	if line >= usedRows {
+		line = usedRows - 1
+	}
+	return line
+}
+
+// scrolls so the given line is in the middle of the screen
+func scrollToLineCentered(line int) {
+	target := line
+	if settings.WordWrap {
+		wrap.update()
+		if line < len(wrap.lineStart) {
+			target = wrap.lineStart[line]
+		}
+	}
+	visibleRows := getVisibleRows()
+	activePane.scrollY = target - visibleRows/2
+
+	maxScrollY := totalDisplayRows() - visibleRows
+	if activePane.scrollY > maxScrollY {
+		activePane.scrollY = maxScrollY
+	}
+	if activePane.scrollY < 0 {
+		activePane.scrollY = 0
+	}
+}
+
+// click or drag on the minimap scrolls
+func handleMinimapInput() {
+	if !settings.Minimap {
+		return
+	}
+	x, y, w, h := minimapRect()
+	mouseX := int(rl.GetMouseX())
+	mouseY := int(rl.GetMouseY())
+	over := mouseX >= x && mouseX < x+w && mouseY >= y && mouseY < y+h
+
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && over {
+		minimapDragging = true
+	}
+	if !rl.IsMouseButtonDown(rl.MouseLeftButton) {
+		minimapDragging = false
+	}
+	if minimapDragging {
+		scrollToLineCentered(minimapLineAt(mouseY))
+	}
+}
+
+func drawMinimap(rows []visualRow) {
+	if !settings.Minimap {
+		return
+	}
+	x, y, w, h := minimapRect()
+	lineHeight := minimapLineHeight()
+
+	rl.DrawRectangle(int32(x), int32(y), int32(w), int32(h), MinimapBackgroundColor)
+
+	// one pass over the pixel rows, so huge files cost the same as small ones
+	for py := 0; py < h; py++ {
+		line := int(float32(py) / lineHeight)
+		if line >= usedRows {
+			break
+		}
+		if lineHeight >= 1 && int(float32(line)*lineHeight) != py {
+			continue
+		}
+		drawMinimapLine(line, x, y+py, w)
+	}
+
+	markerHeight := int32(max(2, int(lineHeight)))
+
+	// visible region
+	if len(rows) > 0 {
+		top := minimapLineY(rows[0].line)
+		bottom := minimapLineY(rows[len(rows)-1].line + 1)
+		rl.DrawRectangle(int32(x), int32(top), int32(w), int32(max(2, bottom-top)), MinimapViewportColor)
+	}
+
+	// modified lines on the left edge, search matches on the right edge
+	for _, line := range modifiedLines() {
+		rl.DrawRectangle(int32(x), int32(minimapLineY(line)), 2, markerHeight, MinimapModifiedColor)
+	}
+	for _, line := range searchMatchLines() {
+		rl.DrawRectangle(int32(x+w-4), int32(minimapLineY(line)), 4, markerHeight, MinimapMatchColor)
+	}
+
+	// cursor line across the whole width
+	rl.DrawRectangle(int32(x), int32(minimapLineY(cursor.y)), int32(w), 2, MinimapCursorColor)
+}
+
+// draws runs of non space characters of a line as 1px high bars in their token color
+func drawMinimapLine(line, x, y, w int) {
+	content := rowContent(line)
+	kinds := highlighter.lineKinds(line)
+
+	for i := 0; i < len(content) && i < w; {
+		if content[i] == ' ' {
+			i++
+			continue
+		}
+		kind := TokenDefault
+		if i < len(kinds) {
+			kind = kinds[i]
+		}
+		start := i
+		for i < len(content) && i < w && content[i] != ' ' && (i >= len(kinds) || kinds[i] == kind) {
+			i++
+		}
+
+		color := EditorTextColor
+		if kind != TokenDefault {
+			color = SyntaxColors[tokenKindNames[kind]]
+		}
+		color.A = 140
+		rl.DrawRectangle(int32(x+start), int32(y), int32(i-start), 1, color)
+	}
+}
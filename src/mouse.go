@@ -0,0 +1,624 @@
+package main
+
+import (
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Mouse in the text area: click places the cursor, drag selects, double-click
+// selects a word and triple-click a line (dragging after them extends by words or
+// lines), Shift+click extends the selection. Dragging past the edges of the text
+// scrolls, faster the further away the mouse is. A selection dragged somewhere
+// else is moved there, with Ctrl copied, in one undo step. Middle-click pastes the
+// primary selection, the text last selected with the mouse. The scroll bars can
+// be dragged by their thumb, a click on the track pages.
+
+const multiClickTime = 400 * time.Millisecond
+const textDragThreshold = 4 // pixels the mouse moves before a press in the selection drags it
+
+type mouseSelectMode int
+
+const (
+	selectChars mouseSelectMode = iota
+	selectWords
+	selectLines
+	dragText // the selection is dragged to a new place
+)
+
+var mouseSelect struct {
+	active bool // left button went down in the text of the active pane
+	mode   mouseSelectMode
+	// word or line clicked first, dragging keeps it selected
+	anchorStart, anchorEnd, anchorY int
+
+	clicks    int
+	lastClick time.Time
+	lastX     int
+	lastY     int
+	lastPane  *Pane
+}
+
+// text last selected with the mouse, pasted by a middle-click
+var primarySelection string
+
+var textDrag struct {
+	dragging         bool // moved far enough to be a drag, else a click
+	pressX, pressY   int  // mouse on press
+	clickX, clickY   int  // text position of the press
+	targetX, targetY int  // where the text is dropped
+}
+
+// rows and columns scrolled so far but not applied, auto scroll moves by whole ones
+var autoScroll struct {
+	rows, cols float32
+}
+
+// x of the end of line y: on its newline, or after the last character
+func lineEndX(y int) int {
+	if y < 0 || y >= usedRows {
+		return 0
+	}
+	w := getRowWidth(y)
+	if w > 0 && textGrid[y][w-1] == '\n' {
+		w--
+	}
+	return min(w, editorCols-1)
+}
+
+// text position under the mouse. Positions past the end of a line land on its
+// end, below the last line on the end of the document. ok is false outside the
+// text area, with clamp the mouse is moved inside it first.
+func textPosAt(mouseX, mouseY int, clamp bool) (int, int, bool) {
+	bottom := textAreaY() + getVisibleRows()*CHAR_IMAGE_HEIGHT
+	if clamp {
+		mouseX = max(textAreaX(), min(mouseX, textAreaRight()-1))
+		mouseY = max(textAreaY(), min(mouseY, bottom-1))
+	}
+	if mouseX < textAreaX() || mouseX >= textAreaRight() || mouseY < textAreaY() || mouseY >= bottom {
+		return 0, 0, false
+	}
+	col := (mouseX - textAreaX()) / CHAR_IMAGE_WIDTH
+	row := (mouseY - textAreaY()) / CHAR_IMAGE_HEIGHT
+
+	rows := displayRows()
+	if row >= len(rows) {
+		y := usedRows - 1
+		return lineEndX(y), y, true
+	}
+	r := rows[row]
+	x := r.start + col
+	if !r.last && x >= r.end {
+		x = r.end - 1
+	}
+	return min(x, lineEndX(r.line)), r.line, true
+}
+
+// counts clicks on the same spot in quick succession, 1 to 3 then again from 1
+func countClick(x, y int) int {
+	now := time.Now()
+	if mouseSelect.lastPane == activePane && y == mouseSelect.lastY && abs(x-mouseSelect.lastX) <= 1 &&
+		now.Sub(mouseSelect.lastClick) < multiClickTime {
+		mouseSelect.clicks = mouseSelect.clicks%3 + 1
+	} else {
+		mouseSelect.clicks = 1
+	}
+	mouseSelect.lastClick = now
+	mouseSelect.lastX, mouseSelect.lastY = x, y
+	mouseSelect.lastPane = activePane
+	return mouseSelect.clicks
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// word around x, or just the cell when there is no word
+func wordOrCell(x, y int) (int, int) {
+	if start, end, ok := wordBounds(x, y); ok {
+		return start, end
+	}
+	return x, x + 1
+}
+
+func setSelection(startX, startY, endX, endY int) {
+	selection.reset()
+	selection.Active = true
+	selection.StartX, selection.StartY = startX, startY
+	selection.EndX, selection.EndY = endX, endY
+}
+
+// extends a word or line selection made by a double or triple click to (x, y)
+func extendUnitSelection(x, y int) {
+	anchorY := mouseSelect.anchorY
+	before := y < anchorY || (y == anchorY && x < mouseSelect.anchorStart)
+
+	switch mouseSelect.mode {
+	case selectWords:
+		start, end := wordOrCell(x, y)
+		if before {
+			setSelection(mouseSelect.anchorEnd-1, anchorY, start, y)
+			cursor.x, cursor.y = start, y
+		} else {
+			setSelection(mouseSelect.anchorStart, anchorY, end-1, y)
+			cursor.x, cursor.y = end, y
+		}
+	case selectLines:
+		if y < anchorY {
+			setSelection(max(0, getRowWidth(anchorY)-1), anchorY, 0, y)
+		} else {
+			setSelection(0, anchorY, max(0, getRowWidth(y)-1), y)
+		}
+		selection.Active = selection.StartY != selection.EndY || getRowWidth(y) > 0
+		cursor.x, cursor.y = 0, y
+	}
+}
+
+// starts a word or line selection at (x, y) that follows the mouse while dragged
+func startUnitSelection(mode mouseSelectMode, x, y int) {
+	mouseSelect.active = true
+	mouseSelect.mode = mode
+	mouseSelect.anchorY = y
+	mouseSelect.anchorStart, mouseSelect.anchorEnd = 0, 0
+	if mode == selectWords {
+		mouseSelect.anchorStart, mouseSelect.anchorEnd = wordOrCell(x, y)
+	}
+	extendUnitSelection(x, y)
+	if mode == selectWords && !isWordChar(textGrid[y][x]) {
+		// nothing to select between words, only place the cursor
+		selection.reset()
+		cursor.x, cursor.y = x, y
+	}
+}
+
+func pastePrimarySelection(x, y int) {
+	cursor.x, cursor.y = x, y
+	selection.reset()
+	if primarySelection == "" {
+		return
+	}
+	ensureGridCapacityForPaste(cursor.x, cursor.y, primarySelection)
+	undoStack = append(undoStack, takeSnapshot())
+	redoStack = nil
+
+	insertStringAtCursor(primarySelection)
+	ensureCursorVisible(cursor)
+}
+
+// clicks, drags and releases on the text of the active pane
+func handleTextMouse() {
+	if handleScrollbarInput() {
+		return
+	}
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+	if mouseOverNotesPanel() {
+		return
+	}
+
+	if rl.IsMouseButtonPressed(rl.MouseRightButton) {
+		if x, y, ok := textPosAt(mouseX, mouseY, false); ok {
+			openTextContextMenu(x, y)
+		}
+		return
+	}
+
+	if rl.IsMouseButtonPressed(rl.MouseMiddleButton) {
+		if x, y, ok := textPosAt(mouseX, mouseY, false); ok {
+			pastePrimarySelection(x, y)
+		}
+		return
+	}
+
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+		// click on a line number selects the line, dragging adds lines
+		if mouseX >= activePane.x && mouseX < textAreaX() && gutterWidth() > 0 &&
+			mouseY >= textAreaY() && mouseY < activePane.y+activePane.h {
+			if line, ok := screenRowLine((mouseY - textAreaY()) / CHAR_IMAGE_HEIGHT); ok {
+				startUnitSelection(selectLines, 0, line)
+			}
+			return
+		}
+
+		x, y, ok := textPosAt(mouseX, mouseY, false)
+		if !ok {
+			return
+		}
+		if ctrlDown() && !shiftDown() {
+			if l, ok := wikiLinkAt(x, y); ok {
+				followWikiLink(l.target)
+				return
+			}
+		}
+		clicks := countClick(x, y)
+		switch {
+		case shiftDown():
+			anchorX, anchorY := cursor.x, cursor.y
+			if selection.Active {
+				anchorX, anchorY = selection.StartX, selection.StartY
+			}
+			setSelection(anchorX, anchorY, x, y)
+			cursor.x, cursor.y = x, y
+			mouseSelect.active = true
+			mouseSelect.mode = selectChars
+		case clicks == 2:
+			startUnitSelection(selectWords, x, y)
+		case clicks == 3:
+			startUnitSelection(selectLines, x, y)
+		case selection.Active && isCellSelected(x, y) && textGrid[y][x] != 0:
+			// press on the selection, a drag moves it
+			mouseSelect.active = true
+			mouseSelect.mode = dragText
+			textDrag.dragging = false
+			textDrag.pressX, textDrag.pressY = mouseX, mouseY
+			textDrag.clickX, textDrag.clickY = x, y
+			textDrag.targetX, textDrag.targetY = x, y
+			return
+		default:
+			cursor.x, cursor.y = x, y
+			setSelection(x, y, x, y)
+			mouseSelect.active = true
+			mouseSelect.mode = selectChars
+		}
+		ensureCursorVisible(cursor)
+		return
+	}
+
+	if !mouseSelect.active {
+		return
+	}
+
+	if rl.IsMouseButtonDown(rl.MouseLeftButton) {
+		if mouseSelect.mode == dragText && !textDrag.dragging {
+			if abs(mouseX-textDrag.pressX) < textDragThreshold && abs(mouseY-textDrag.pressY) < textDragThreshold {
+				return
+			}
+			textDrag.dragging = true
+		}
+		autoScrollTowards(mouseX, mouseY)
+		x, y, _ := textPosAt(mouseX, mouseY, true)
+		switch mouseSelect.mode {
+		case dragText:
+			textDrag.targetX, textDrag.targetY = x, y
+		case selectChars:
+			if selection.Active {
+				selection.EndX, selection.EndY = x, y
+			}
+		default:
+			extendUnitSelection(x, y)
+		}
+		return
+	}
+
+	// released
+	mouseSelect.active = false
+	autoScroll.rows, autoScroll.cols = 0, 0
+	if mouseSelect.mode == dragText {
+		if textDrag.dragging {
+			textDrag.dragging = false
+			dropSelection(textDrag.targetX, textDrag.targetY, ctrlDown())
+		} else {
+			// a click on the selection without dragging
+			selection.reset()
+			cursor.x, cursor.y = textDrag.clickX, textDrag.clickY
+		}
+		clampCursor()
+		return
+	}
+	if !selection.Active {
+		clampCursor()
+		return
+	}
+	if mouseSelect.mode == selectChars {
+		if selection.StartX == selection.EndX && selection.StartY == selection.EndY {
+			selection.Active = false // no drag = no selection
+			clampCursor()
+			return
+		}
+		cursor.x, cursor.y = selection.EndX, selection.EndY
+	}
+	clampCursor()
+	primarySelection = getSelectedText()
+}
+
+// scrolls while the mouse is dragged past the edges of the text area, the
+// further past the faster
+func autoScrollTowards(mouseX, mouseY int) {
+	dt := rl.GetFrameTime()
+	top, bottom := textAreaY(), textAreaY()+getVisibleRows()*CHAR_IMAGE_HEIGHT
+	switch {
+	case mouseY < top:
+		autoScroll.rows -= autoScrollSpeed(top-mouseY) * dt
+	case mouseY >= bottom:
+		autoScroll.rows += autoScrollSpeed(mouseY-bottom+1) * dt
+	default:
+		autoScroll.rows = 0
+	}
+	if rows := int(autoScroll.rows); rows != 0 {
+		autoScroll.rows -= float32(rows)
+		maxScrollY := max(0, totalDisplayRows()-getVisibleRows())
+		activePane.scrollY = max(0, min(activePane.scrollY+rows, maxScrollY))
+	}
+
+	if settings.WordWrap {
+		return
+	}
+	switch {
+	case mouseX < textAreaX():
+		autoScroll.cols -= autoScrollSpeed(textAreaX()-mouseX) * dt
+	case mouseX >= textAreaRight():
+		autoScroll.cols += autoScrollSpeed(mouseX-textAreaRight()+1) * dt
+	default:
+		autoScroll.cols = 0
+	}
+	if cols := int(autoScroll.cols); cols != 0 {
+		autoScroll.cols -= float32(cols)
+		maxScrollX := max(0, getMaxContentWidth()-getVisibleCols())
+		activePane.scrollX = max(0, min(activePane.scrollX+cols, maxScrollX))
+	}
+}
+
+// rows or columns per second for a mouse the given pixels past the edge
+func autoScrollSpeed(distance int) float32 {
+	return 5 + float32(distance)*0.6
+}
+
+// ------------------------------------------------------------------------------------
+
+// true when (ax, ay) comes before (bx, by) in the text
+func posBefore(ax, ay, bx, by int) bool {
+	return ay < by || (ay == by && ax < bx)
+}
+
+// position right after the cell (x, y), the start of the next line when the cell
+// ends its line
+func posAfter(x, y int) (int, int) {
+	end := lineEndX(y)
+	if x < end {
+		return x + 1, y
+	}
+	if y+1 < usedRows {
+		return 0, y + 1
+	}
+	return end, y
+}
+
+// selection start and end (inclusive) in text order
+func selectionBounds() (int, int, int, int) {
+	sx, sy, ex, ey := selection.StartX, selection.StartY, selection.EndX, selection.EndY
+	if posBefore(ex, ey, sx, sy) {
+		sx, sy, ex, ey = ex, ey, sx, sy
+	}
+	return sx, sy, ex, ey
+}
+
+// removes the text from (sx, sy) up to, not including, (ex, ey)
+func deleteRange(sx, sy, ex, ey int) {
+	cursor.x, cursor.y = ex, ey
+	for posBefore(sx, sy, cursor.x, cursor.y) {
+		cursor.backspaceSingle()
+	}
+	recountUsedRows()
+}
+
+// moves the selected text to (x, y), or copies it there, as one undo step. The
+// dropped text is selected afterwards.
+func dropSelection(x, y int, copy bool) {
+	text := getSelectedText()
+	if text == "" {
+		return
+	}
+	sx, sy, ex, ey := selectionBounds()
+	ax, ay := posAfter(ex, ey)
+	if !copy && !posBefore(x, y, sx, sy) && posBefore(x, y, ax, ay) {
+		// dropped on itself
+		return
+	}
+
+	undoStack = append(undoStack, takeSnapshot())
+	redoStack = nil
+	if !copy {
+		deleteRange(sx, sy, ax, ay)
+		if !posBefore(x, y, ax, ay) {
+			// the target moved up with the text after the removed block
+			if y == ay {
+				x = sx + x - ax
+			}
+			y -= ay - sy
+		}
+	}
+
+	cursor.x, cursor.y = x, y
+	ensureGridCapacityForPaste(x, y, text)
+	insertStringAtCursor(text)
+	usedRows = max(usedRows, cursor.y+1)
+
+	endX, endY := cursor.x-1, cursor.y
+	if cursor.x == 0 && cursor.y > 0 {
+		endY--
+		endX = lineEndX(endY)
+	}
+	setSelection(x, y, endX, endY)
+	primarySelection = text
+	ensureCursorVisible(cursor)
+}
+
+// caret where dragged text would be dropped
+func drawDropMarker(rows []visualRow) {
+	if mouseSelect.mode != dragText || !textDrag.dragging || activePane != focusedPane {
+		return
+	}
+	for i, row := range rows {
+		if row.line == textDrag.targetY && row.holdsColumn(textDrag.targetX) {
+			rl.DrawRectangle(
+				int32((textDrag.targetX-row.start)*CHAR_IMAGE_WIDTH+textAreaX()),
+				int32(i*CHAR_IMAGE_HEIGHT+textAreaY()+editorYPadding),
+				2, CHAR_IMAGE_HEIGHT, ModernAccent)
+			return
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------
+
+// a scroll bar of the active pane, track and thumb along its axis
+type scrollbar struct {
+	vertical   bool
+	x, y, w, h int // track
+	thumb      int // thumb offset from the start of the track
+	thumbLen   int
+	page       int // rows or columns a click on the track scrolls
+	maxScroll  int
+}
+
+var scrollbarDrag struct {
+	pane     *Pane
+	vertical bool
+	grab     int // mouse offset inside the thumb
+}
+
+func verticalScrollbar() (scrollbar, bool) {
+	visibleRows := getVisibleRows()
+	totalRows := totalDisplayRows()
+	if totalRows <= visibleRows {
+		return scrollbar{}, false
+	}
+	s := scrollbar{
+		vertical:  true,
+		x:         activePane.x + activePane.w - 10,
+		y:         activePane.y,
+		w:         8,
+		h:         activePane.h,
+		page:      visibleRows,
+		maxScroll: totalRows - visibleRows,
+	}
+	s.thumbLen = max(10, s.h*visibleRows/totalRows)
+	s.thumb = (s.h - s.thumbLen) * min(activePane.scrollY, s.maxScroll) / s.maxScroll
+	return s, true
+}
+
+func horizontalScrollbar() (scrollbar, bool) {
+	visibleCols := getVisibleCols()
+	maxContentWidth := getMaxContentWidth()
+	if settings.WordWrap || maxContentWidth <= visibleCols {
+		return scrollbar{}, false
+	}
+	s := scrollbar{
+		x:         textAreaX(),
+		y:         activePane.y + activePane.h - 10,
+		w:         textAreaRight() - textAreaX() - 15,
+		h:         6,
+		page:      visibleCols,
+		maxScroll: maxContentWidth - visibleCols,
+	}
+	s.thumbLen = max(10, s.w*visibleCols/maxContentWidth)
+	s.thumb = (s.w - s.thumbLen) * min(activePane.scrollX, s.maxScroll) / s.maxScroll
+	return s, true
+}
+
+func (s scrollbar) length() int {
+	if s.vertical {
+		return s.h
+	}
+	return s.w
+}
+
+// mouse position along the track
+func (s scrollbar) along(mouseX, mouseY int) int {
+	if s.vertical {
+		return mouseY - s.y
+	}
+	return mouseX - s.x
+}
+
+func (s scrollbar) contains(mouseX, mouseY int) bool {
+	if s.vertical {
+		return mouseX >= s.x-1 && mouseX < s.x+s.w && mouseY >= s.y && mouseY < s.y+s.h
+	}
+	return mouseX >= s.x && mouseX < s.x+s.w && mouseY >= s.y-2 && mouseY < s.y+s.h+2
+}
+
+func (s scrollbar) setScroll(value int) {
+	value = max(0, min(value, s.maxScroll))
+	if s.vertical {
+		activePane.scrollY = value
+	} else {
+		activePane.scrollX = value
+	}
+}
+
+func (s scrollbar) scroll() int {
+	if s.vertical {
+		return activePane.scrollY
+	}
+	return activePane.scrollX
+}
+
+// thumb dragging and paging on the track, true when the mouse was used
+func handleScrollbarInput() bool {
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+
+	if scrollbarDrag.pane != nil {
+		if !rl.IsMouseButtonDown(rl.MouseLeftButton) {
+			scrollbarDrag.pane = nil
+			return true
+		}
+		withPane(scrollbarDrag.pane, func() {
+			s, ok := verticalScrollbar()
+			if !scrollbarDrag.vertical {
+				s, ok = horizontalScrollbar()
+			}
+			if !ok || s.length() <= s.thumbLen {
+				return
+			}
+			pos := s.along(mouseX, mouseY) - scrollbarDrag.grab
+			s.setScroll((pos*s.maxScroll + (s.length()-s.thumbLen)/2) / (s.length() - s.thumbLen))
+		})
+		return true
+	}
+
+	if !rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+		return false
+	}
+	for _, get := range []func() (scrollbar, bool){verticalScrollbar, horizontalScrollbar} {
+		s, ok := get()
+		if !ok || !s.contains(mouseX, mouseY) {
+			continue
+		}
+		pos := s.along(mouseX, mouseY)
+		switch {
+		case pos < s.thumb:
+			s.setScroll(s.scroll() - s.page)
+		case pos >= s.thumb+s.thumbLen:
+			s.setScroll(s.scroll() + s.page)
+		default:
+			scrollbarDrag.pane = activePane
+			scrollbarDrag.vertical = s.vertical
+			scrollbarDrag.grab = pos - s.thumb
+		}
+		return true
+	}
+	return false
+}
+
+func drawScrollIndicators() {
+	dragged := scrollbarDrag.pane == activePane
+	if s, ok := verticalScrollbar(); ok {
+		color := ScrollThumbColor
+		if dragged && scrollbarDrag.vertical {
+			color = ModernAccent
+		}
+		rl.DrawRectangle(int32(s.x), int32(s.y), int32(s.w), int32(s.h), ScrollTrackColor)
+		rl.DrawRectangle(int32(s.x+1), int32(s.y+s.thumb), 6, int32(s.thumbLen), color)
+	}
+	if s, ok := horizontalScrollbar(); ok {
+		color := ScrollThumbColor
+		if dragged && !scrollbarDrag.vertical {
+			color = ModernAccent
+		}
+		rl.DrawRectangle(int32(s.x), int32(s.y), int32(s.w), int32(s.h), ScrollTrackColor)
+		rl.DrawRectangle(int32(s.x+s.thumb), int32(s.y+1), int32(s.thumbLen), 4, color)
+	}
+}
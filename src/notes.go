@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Notes live in notebooks, named folders of notes. The default notebook "Notes" is
+// at $EDITOR2_NOTES, else the "notesRoot" of the config, else
+// $XDG_DATA_HOME/editor2/notes (~/.local/share/editor2/notes). More notebooks are
+// listed under "notebooks" in the config, the one in use is stored as "notebook".
+
+const defaultNotebook = "Notes"
+
+type Notebook struct {
+	Name string `json:"name"`
+	Root string `json:"root"`
+}
+
+// returns the editor data folder ($XDG_DATA_HOME/editor2 or ~/.local/share/editor2)
+func dataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "editor2")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".editor2"
+	}
+	return filepath.Join(home, ".local", "share", "editor2")
+}
+
+// replaces a leading ~ with the home folder
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// the default notebook first, then the ones of the config
+func notebooks() []Notebook {
+	root := os.Getenv("EDITOR2_NOTES")
+	if root == "" {
+		root = settings.NotesRoot
+	}
+	if root == "" {
+		root = filepath.Join(dataDir(), "notes")
+	}
+	list := []Notebook{{Name: defaultNotebook, Root: root}}
+	for _, nb := range settings.Notebooks {
+		if nb.Name != "" && nb.Root != "" && nb.Name != defaultNotebook {
+			list = append(list, nb)
+		}
+	}
+	for i := range list {
+		if abs, err := filepath.Abs(expandHome(list[i].Root)); err == nil {
+			list[i].Root = abs
+		}
+	}
+	return list
+}
+
+// the notebook in use, the default one when the configured name is unknown
+func currentNotebook() Notebook {
+	list := notebooks()
+	for _, nb := range list {
+		if nb.Name == settings.Notebook {
+			return nb
+		}
+	}
+	return list[0]
+}
+
+func notesRoot() string {
+	return currentNotebook().Root
+}
+
+func switchNotebook(name string) {
+	settings.Notebook = name
+	if err := saveSetting("notebook", name); err != nil {
+		notifyError("Could not save config.json: %v", err)
+	}
+	ui.NotesPath = ""
+	ui.NotesTag = ""
+	ui.NotesScroll = 0
+	reloadNotesList()
+	editorStatus = "Notebook: " + currentNotebook().Name
+}
+
+// one checked item per notebook, for the Notes menu and panel
+func notebookItems() []MenuItem {
+	current := currentNotebook().Name
+	var items []MenuItem
+	for _, nb := range notebooks() {
+		name := nb.Name
+		items = append(items, MenuItem{Label: name, Run: func() { switchNotebook(name) }, Checked: name == current})
+	}
+	return items
+}
+
+// true when path is inside one of the notebooks
+func isNote(path string) bool {
+	if path == "" || path == "Untitled" {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, nb := range notebooks() {
+		if abs != nb.Root && pathWithin(abs, nb.Root) {
+			return true
+		}
+	}
+	return false
+}
+
+// creates today's note at the note pattern and fills it with the template, or
+// saves the current buffer into it when template is ""
+func createNote(title, template string) (string, error) {
+	now := time.Now()
+	filePath := notePath(notesRoot(), now, title)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return "", fmt.Errorf("creating folder %s: %w", filepath.Dir(filePath), err)
+	}
+	if _, err := os.Stat(filePath); err == nil {
+		return "", fmt.Errorf("%s already exists", filepath.Base(filePath))
+	}
+
+	if template != "" {
+		text, err := noteTemplateText(template)
+		if err != nil {
+			return "", err
+		}
+		// the note replaces the buffer, changes to a file that is no note are not
+		// saved behind the user's back
+		if !isNote(currentFile) && len(modifiedLines()) > 0 {
+			return "", fmt.Errorf("%s has unsaved changes, save or revert them first", filepath.Base(currentFile))
+		}
+		saveModifiedNote()
+		loadStringIntoTextGrid(expandNoteTemplate(text, now, title, filePath))
+		resetUndoRedoStacks()
+	}
+	if err := saveBufferAs(filePath); err != nil {
+		return "", err
+	}
+	noteOpened(filePath)
+	refreshFileLists("")
+	return filePath, nil
+}
+
+// ------------------------------------------------------------------------------------
+
+// The layout of a notebook comes from "notePattern" in the config, a path below the
+// root with YYYY, YY, MM and DD for the date and {{title}} for the title, for
+// example "YYYY/MM/YYYY-MM-DD.md". Without {{title}} a title is added as
+// " - title" before the extension. The folders and names of the pattern are also
+// how the Notes panel orders its lists, newest first.
+
+const defaultNotePattern = "MM-YYYY/DD-MM-YYYY.txt"
+
+func notePattern() string {
+	p := strings.Trim(filepath.ToSlash(strings.TrimSpace(settings.NotePattern)), "/")
+	if p == "" {
+		return defaultNotePattern
+	}
+	if !isNoteFile(p) {
+		p += ".txt"
+	}
+	return p
+}
+
+// path of the note of day t below root
+func notePath(root string, t time.Time, title string) string {
+	p := notePattern()
+	ext := filepath.Ext(p)
+	p = strings.NewReplacer("YYYY", t.Format("2006"), "YY", t.Format("06"), "MM", t.Format("01"), "DD", t.Format("02")).Replace(strings.TrimSuffix(p, ext))
+	title = strings.ReplaceAll(strings.TrimSpace(title), "/", "-")
+	switch {
+	case strings.Contains(p, "{{title}}") && title != "":
+		p = strings.ReplaceAll(p, "{{title}}", title)
+	case strings.Contains(p, "{{title}}"):
+		p = strings.TrimRight(strings.ReplaceAll(p, "{{title}}", ""), " -_")
+	case title != "":
+		p += " - " + title
+	}
+	return filepath.Join(root, filepath.FromSlash(p)+ext)
+}
+
+// the date at the start of a file or folder name, read with one part of the pattern
+func patternDate(part, name string) (time.Time, bool) {
+	part = strings.TrimSuffix(part, filepath.Ext(part))
+	if i := strings.Index(part, "{{title}}"); i != -1 {
+		part = part[:i]
+	}
+	part = strings.TrimRight(part, " -_")
+	if !strings.Contains(part, "YY") && !strings.Contains(part, "MM") && !strings.Contains(part, "DD") {
+		return time.Time{}, false
+	}
+	layout := strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02").Replace(part)
+	name = strings.TrimSuffix(name, "/")
+	if len(name) < len(layout) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(layout, name[:len(layout)])
+	return t, err == nil
+}
+
+// the day of a note file name, by the note pattern or a dd-mm-yyyy start
+func noteNameDate(name string) (time.Time, bool) {
+	parts := strings.Split(notePattern(), "/")
+	if t, ok := patternDate(parts[len(parts)-1], name); ok {
+		return t, true
+	}
+	return patternDate("DD-MM-YYYY", name)
+}
+
+// orders the entries of a notes folder newest first by the pattern part of their
+// level, names without a date after them
+func sortByPatternDate(names []string, part string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		ti, oki := patternDate(part, names[i])
+		tj, okj := patternDate(part, names[j])
+		switch {
+		case oki && okj && !ti.Equal(tj):
+			return ti.After(tj)
+		case oki != okj:
+			return oki
+		}
+		return names[i] > names[j]
+	})
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Notes search (Ctrl+Shift+F): full-text search over every note of the current
+// notebook, answered by the notes index. Words match word starts and all have to
+// be in a note, "quoted phrases" have to appear as written, /regex/ searches the
+// lines with a case-insensitive regular expression. Results show the note, its
+// date and the best matching line; Enter opens the note at that line.
+
+const notesSearchRowHeight = 40
+const notesSearchDelay = 150 * time.Millisecond
+
+type notesSearchPanel struct {
+	noteResultList
+	index *notesIndex
+	query string // query of results
+	typed time.Time
+	err   string
+	took  time.Duration
+}
+
+// notes with their matching line, the results of the search and the backlinks
+type noteResultList struct {
+	results  []noteMatch
+	selected int
+	scroll   int
+}
+
+var notesSearchModal = &notesSearchPanel{}
+
+func openNotesSearch() {
+	s := notesSearchModal
+	s.index = notesIndexFor(notesRoot())
+	s.index.sync()
+	s.query = ""
+	s.results = nil
+	s.err = ""
+	s.selected = 0
+	s.scroll = 0
+	ui.InputBoxes = []*InputBox{
+		{
+			Rect:     rl.NewRectangle(0, 0, 0, 32),
+			MaxChars: 256,
+			History:  "notesSearch",
+		},
+	}
+	// a selection on one line is the first search
+	if selection.Active && selection.StartY == selection.EndY {
+		ui.InputBoxes[0].SetText(strings.TrimSpace(getSelectedText()))
+		ui.InputBoxes[0].SelectAll()
+	}
+	pushModal(s)
+	s.run()
+}
+
+func (s *notesSearchPanel) close() {
+	closeModal(s)
+	ui.InputBoxes = nil
+}
+
+func (s *notesSearchPanel) run() {
+	text := ""
+	if len(ui.InputBoxes) > 0 {
+		text = ui.InputBoxes[0].Text
+	}
+	s.query = text
+	s.results = nil
+	s.err = ""
+	s.selected = 0
+	s.scroll = 0
+	q, err := parseNotesQuery(text)
+	if err != nil {
+		s.err = "Invalid regex: " + err.Error()
+		return
+	}
+	start := time.Now()
+	s.results = s.index.search(q)
+	s.took = time.Since(start)
+}
+
+func (s *notesSearchPanel) open(m noteMatch) {
+	ui.InputBoxes[0].AddToHistory()
+	s.close()
+	openNoteMatch(m)
+}
+
+// opens the note of a result with the match selected
+func openNoteMatch(m noteMatch) {
+	openNote(m.Path)
+	if currentFile != m.Path {
+		return
+	}
+	cursor.y = min(m.Line, usedRows-1)
+	cursor.x = min(m.Col, getRowWidth(cursor.y))
+	if m.Len > 0 && cursor.x+m.Len <= getRowWidth(cursor.y) {
+		selection.Active = true
+		selection.StartX, selection.StartY = cursor.x, cursor.y
+		selection.EndX, selection.EndY = cursor.x+m.Len-1, cursor.y
+	}
+	scrollToLineCentered(cursor.y)
+	ensureCursorVisible(cursor)
+}
+
+// date of a note: from a dated name, else when it was last changed
+func noteDate(m noteMatch) string {
+	if t, ok := noteNameDate(path.Base(m.Rel)); ok {
+		return t.Format("2006-01-02")
+	}
+	return time.Unix(0, m.ModTime).Format("2006-01-02")
+}
+
+// the part of the snippet around the first match that fits maxChars
+func snippetWindow(m noteMatch, maxChars int) (string, []int) {
+	text, positions := m.Snippet, m.Positions
+	trimmed := strings.TrimLeft(text, " ")
+	cut := len(text) - len(trimmed)
+	if m.Col-cut > maxChars*2/3 {
+		cut = m.Col - maxChars/3
+	}
+	if cut > 0 {
+		text = text[cut:]
+		shifted := make([]int, 0, len(positions))
+		for _, p := range positions {
+			if p >= cut {
+				shifted = append(shifted, p-cut)
+			}
+		}
+		positions = shifted
+	}
+	if len(text) > maxChars {
+		text = text[:maxChars]
+	}
+	return text, positions
+}
+
+func (s *notesSearchPanel) drawModal(top bool) {
+	if len(ui.InputBoxes) == 0 {
+		closeModal(s)
+		return
+	}
+	box := ui.InputBoxes[0]
+
+	w := int32(min(900, windowWidth-40))
+	h := int32(windowHeight - editorTopPadding - editorBottomPadding - 40)
+	x := int32(windowWidth)/2 - w/2
+	y := int32(editorTopPadding + 20)
+	listY := y + 48 + 24
+	visible := max(1, int(h-48-24-8)/notesSearchRowHeight)
+
+	if top {
+		before := box.Text
+		box.HandleInput()
+		if box.Text != before {
+			s.typed = time.Now()
+		}
+		if box.Text != s.query && time.Since(s.typed) >= notesSearchDelay {
+			s.run()
+		}
+
+		switch {
+		case rl.IsKeyPressed(rl.KeyEscape):
+			s.close()
+			return
+		case rl.IsKeyPressed(rl.KeyEnter):
+			if box.Text != s.query {
+				s.run()
+			}
+			if s.selected < len(s.results) {
+				s.open(s.results[s.selected])
+				return
+			}
+		default:
+			s.handleKeys(visible)
+		}
+	}
+
+	rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), ModernOverlay)
+	drawShadow(float32(x), float32(y), float32(w), float32(h), 4, 8)
+	rl.DrawRectangle(x, y, w, h, ModernMedium)
+	rl.DrawRectangleLines(x, y, w, h, ModernBorder)
+
+	box.Rect = rl.NewRectangle(float32(x+8), float32(y+8), float32(w-16), 32)
+	box.Draw()
+
+	status := fmt.Sprintf("%d notes in %s - words, \"phrase\" or /regex/", len(s.index.Notes), currentNotebook().Name)
+	if s.query != "" && s.err == "" {
+		status = fmt.Sprintf("%d results (%d ms), %s", len(s.results), s.took.Milliseconds(), status)
+		if len(s.results) >= maxNoteSearchResults {
+			status = "first " + status
+		}
+	}
+	DrawText(clampText(status, int(w-24)/CHAR_IMAGE_WIDTH), int(x)+12, int(y)+48, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+
+	switch {
+	case s.err != "":
+		DrawText(clampText(s.err, int(w-32)/CHAR_IMAGE_WIDTH), int(x)+16, int(listY)+4, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.danger")
+		return
+	case s.query != "" && len(s.results) == 0:
+		DrawText("No matching notes", int(x)+16, int(listY)+4, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		return
+	}
+
+	if i := s.draw(x, listY, w, visible, top); i != -1 {
+		s.open(s.results[i])
+	}
+}
+
+// Up/Down/PageUp/PageDown and the wheel
+func (l *noteResultList) handleKeys(visible int) {
+	switch {
+	case (rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown)) && l.selected < len(l.results)-1:
+		l.selected++
+	case (rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp)) && l.selected > 0:
+		l.selected--
+	case rl.IsKeyPressed(rl.KeyPageDown):
+		l.selected = max(0, min(l.selected+visible, len(l.results)-1))
+	case rl.IsKeyPressed(rl.KeyPageUp):
+		l.selected = max(l.selected-visible, 0)
+	}
+	if l.selected < l.scroll {
+		l.scroll = l.selected
+	}
+	if l.selected >= l.scroll+visible {
+		l.scroll = l.selected - visible + 1
+	}
+	if wheel := rl.GetMouseWheelMove(); wheel != 0 {
+		l.scroll -= int(wheel * 3)
+		l.scroll = max(0, min(l.scroll, len(l.results)-visible))
+	}
+}
+
+// draws the rows (note and date, line number and snippet), returns the result
+// to open when the selected row was clicked, else -1
+func (l *noteResultList) draw(x, listY, w int32, visible int, top bool) int {
+	maxChars := int(w-32) / CHAR_IMAGE_WIDTH
+	mouseX, mouseY := rl.GetMouseX(), rl.GetMouseY()
+	for i := 0; i < visible; i++ {
+		idx := l.scroll + i
+		if idx >= len(l.results) {
+			break
+		}
+		m := l.results[idx]
+		rowY := listY + int32(i*notesSearchRowHeight)
+		hover := top && mouseX >= x && mouseX < x+w && mouseY >= rowY && mouseY < rowY+notesSearchRowHeight
+
+		if idx == l.selected {
+			rl.DrawRectangle(x+4, rowY, w-8, notesSearchRowHeight-2, ModernLight)
+		} else if hover {
+			rl.DrawRectangle(x+4, rowY, w-8, notesSearchRowHeight-2, ModernDarkButton)
+		}
+
+		date := noteDate(m)
+		DrawText(clampPathLeft(m.Rel, maxChars-len(date)-2), int(x)+12, int(rowY)+3, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+		DrawText(date, int(x+w)-12-len(date)*CHAR_IMAGE_WIDTH, int(rowY)+3, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+
+		lineNo := fmt.Sprintf("%d: ", m.Line+1)
+		DrawText(lineNo, int(x)+20, int(rowY)+20, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		text, positions := snippetWindow(m, maxChars-len(lineNo)-1)
+		drawMatchedText(text, positions, int(x)+20+len(lineNo)*CHAR_IMAGE_WIDTH, int(rowY)+20, maxChars-len(lineNo)-1)
+
+		if hover && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+			if idx == l.selected {
+				return idx
+			}
+			l.selected = idx
+		}
+	}
+	return -1
+}
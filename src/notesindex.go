@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Search index of the notes. Every notebook has an inverted index, word -> notes
+// containing it, stored in <data>/index/ so opening the search does not read every
+// note again. It is synced with the files (new, changed by mod time, deleted) when
+// the search opens and updated right away when a note is saved from the editor.
+// The front matter and #tags of every note are kept with it for the tag browser,
+// its [[links]] for the backlinks.
+
+const maxNoteSearchResults = 200
+const maxIndexedWordLen = 64
+
+// bumped when the stored index changes its form or how notes are read, older
+// indexes are rebuilt
+const notesIndexVersion = 4
+
+type indexedNote struct {
+	ModTime int64    `json:"mtime"`
+	Words   int      `json:"words"` // total number of words, longer notes rank lower
+	Terms   []string `json:"terms"` // distinct words, to drop them when the note changes
+	Meta    noteMeta `json:"meta"`
+	Links   []string `json:"links,omitempty"` // [[link]] targets as link keys
+}
+
+type notesIndex struct {
+	Version int                       `json:"version"`
+	Root    string                    `json:"root"`
+	Notes   map[string]*indexedNote   `json:"notes"` // by slash separated path relative to Root
+	Terms   map[string]map[string]int `json:"terms"` // word -> note -> count
+	dirty   bool
+	synced  bool // synced with the files since the start
+}
+
+var notesIndexes = map[string]*notesIndex{}
+
+func isNoteFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".txt" || ext == ".md"
+}
+
+// lower case words of text, letters and digits
+func noteWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if len(w) <= maxIndexedWordLen {
+			kept = append(kept, w)
+		}
+	}
+	return kept
+}
+
+func notesIndexPath(root string) string {
+	h := fnv.New64a()
+	h.Write([]byte(root))
+	return filepath.Join(dataDir(), "index", fmt.Sprintf("notes-%016x.json", h.Sum64()))
+}
+
+// the index of the notebook at root, read from disk the first time
+func notesIndexFor(root string) *notesIndex {
+	if ix, ok := notesIndexes[root]; ok {
+		return ix
+	}
+	ix := &notesIndex{}
+	if data, err := os.ReadFile(notesIndexPath(root)); err == nil {
+		if err := json.Unmarshal(data, ix); err != nil {
+			notifyWarning("Notes index unreadable, rebuilding it: %v", err)
+			ix = &notesIndex{}
+		}
+	}
+	if ix.Version != notesIndexVersion || ix.Root != root || ix.Notes == nil || ix.Terms == nil {
+		ix = &notesIndex{Version: notesIndexVersion, Root: root, Notes: map[string]*indexedNote{}, Terms: map[string]map[string]int{}}
+	}
+	notesIndexes[root] = ix
+	return ix
+}
+
+func (ix *notesIndex) remove(rel string) {
+	note, ok := ix.Notes[rel]
+	if !ok {
+		return
+	}
+	for _, t := range note.Terms {
+		delete(ix.Terms[t], rel)
+		if len(ix.Terms[t]) == 0 {
+			delete(ix.Terms, t)
+		}
+	}
+	delete(ix.Notes, rel)
+	ix.dirty = true
+}
+
+func (ix *notesIndex) add(rel string, modTime int64, text string) {
+	ix.remove(rel)
+	words := noteWords(text)
+	counts := map[string]int{}
+	for _, w := range words {
+		counts[w]++
+	}
+	note := &indexedNote{ModTime: modTime, Words: len(words), Meta: parseNoteMeta(text), Links: noteLinkTargets(text)}
+	for t, n := range counts {
+		note.Terms = append(note.Terms, t)
+		if ix.Terms[t] == nil {
+			ix.Terms[t] = map[string]int{}
+		}
+		ix.Terms[t][rel] = n
+	}
+	ix.Notes[rel] = note
+	ix.dirty = true
+}
+
+// (re)indexes one file of the notebook
+func (ix *notesIndex) indexFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ix.add(relSlash(ix.Root, path), info.ModTime().UnixNano(), string(data))
+	return nil
+}
+
+// brings the index up to date with the files: new and changed notes are read
+// again, deleted ones dropped
+func (ix *notesIndex) sync() {
+	seen := map[string]bool{}
+	filepath.WalkDir(ix.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != ix.Root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isNoteFile(d.Name()) {
+			return nil
+		}
+		rel := relSlash(ix.Root, p)
+		seen[rel] = true
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if note, ok := ix.Notes[rel]; ok && note.ModTime == info.ModTime().UnixNano() {
+			return nil
+		}
+		if err := ix.indexFile(p); err != nil {
+			notifyWarning("Could not index %s: %v", rel, err)
+		}
+		return nil
+	})
+	for rel := range ix.Notes {
+		if !seen[rel] {
+			ix.remove(rel)
+		}
+	}
+	ix.synced = true
+	ix.save()
+}
+
+func (ix *notesIndex) save() {
+	if !ix.dirty {
+		return
+	}
+	data, err := json.Marshal(ix)
+	if err == nil {
+		path := notesIndexPath(ix.Root)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = os.WriteFile(path, data, 0644)
+		}
+	}
+	if err != nil {
+		notifyError("Could not save the notes index: %v", err)
+		return
+	}
+	ix.dirty = false
+}
+
+// index of the notebook a note is in, nil for other files
+func notebookIndexFor(path string) *notesIndex {
+	abs, err := filepath.Abs(path)
+	if err != nil || !isNoteFile(abs) {
+		return nil
+	}
+	for _, nb := range notebooks() {
+		if abs != nb.Root && pathWithin(abs, nb.Root) {
+			return notesIndexFor(nb.Root)
+		}
+	}
+	return nil
+}
+
+// called after a file was saved, updates the index (words, front matter and tags)
+// of the notebook it is in
+func noteSaved(path string) {
+	ix := notebookIndexFor(path)
+	if ix == nil {
+		return
+	}
+	abs, _ := filepath.Abs(path)
+	if err := ix.indexFile(abs); err != nil {
+		notifyWarning("Could not index %s: %v", filepath.Base(abs), err)
+		return
+	}
+	ix.save()
+}
+
+// number of notes of every tag
+func (ix *notesIndex) tagCounts() map[string]int {
+	counts := map[string]int{}
+	for _, note := range ix.Notes {
+		for _, t := range note.Meta.Tags {
+			counts[t]++
+		}
+	}
+	return counts
+}
+
+// notes with the tag, newest first
+func (ix *notesIndex) notesTagged(tag string) []string {
+	var rels []string
+	for rel, note := range ix.Notes {
+		if slices.Contains(note.Meta.Tags, tag) {
+			rels = append(rels, rel)
+		}
+	}
+	sortNotesNewestFirst(ix, rels)
+	return rels
+}
+
+func sortNotesNewestFirst(ix *notesIndex, rels []string) {
+	sort.Slice(rels, func(i, j int) bool {
+		a, b := ix.Notes[rels[i]], ix.Notes[rels[j]]
+		if a.ModTime != b.ModTime {
+			return a.ModTime > b.ModTime
+		}
+		return rels[i] < rels[j]
+	})
+}
+
+// ------------------------------------------------------------------------------------
+
+// a search: plain words (matching word starts), "quoted phrases" and /regex/
+type notesQuery struct {
+	words   []string
+	phrases []string
+	regex   *regexp.Regexp
+}
+
+func parseNotesQuery(q string) (notesQuery, error) {
+	var query notesQuery
+	q = strings.TrimSpace(q)
+	if len(q) >= 2 && strings.HasPrefix(q, "/") && strings.HasSuffix(q, "/") {
+		re, err := regexp.Compile("(?i)" + q[1:len(q)-1])
+		if err != nil {
+			return query, err
+		}
+		query.regex = re
+		return query, nil
+	}
+	for q != "" {
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			phrase := q[1:]
+			if end != -1 {
+				phrase, q = q[1:end+1], q[end+2:]
+			} else {
+				q = ""
+			}
+			if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+				query.phrases = append(query.phrases, phrase)
+				query.words = append(query.words, noteWords(phrase)...)
+			}
+		} else {
+			end := strings.IndexAny(q, " \t\"")
+			word := q
+			if end != -1 {
+				word, q = q[:end], q[end:]
+			} else {
+				q = ""
+			}
+			query.words = append(query.words, noteWords(word)...)
+		}
+		q = strings.TrimLeft(q, " \t")
+	}
+	return query, nil
+}
+
+func (q notesQuery) empty() bool {
+	return q.regex == nil && len(q.words) == 0
+}
+
+type noteMatch struct {
+	Path      string // absolute
+	Rel       string
+	ModTime   int64
+	Score     float64
+	Line      int // line of the snippet
+	Snippet   string
+	Positions []int // matched bytes of the snippet
+	Col       int   // start of the first match in the line
+	Len       int   // length of the first match
+}
+
+// notes containing a word starting with prefix, with the count of every such word
+func (ix *notesIndex) prefixPostings(prefix string) map[string]int {
+	result := map[string]int{}
+	for t, notes := range ix.Terms {
+		if !strings.HasPrefix(t, prefix) {
+			continue
+		}
+		for rel, n := range notes {
+			result[rel] += n
+		}
+	}
+	return result
+}
+
+// ranks the notes matching q, best first. Word queries are answered by the index,
+// the files are only read to check phrases and find the snippet; a regex reads
+// every note.
+func (ix *notesIndex) search(q notesQuery) []noteMatch {
+	if q.empty() {
+		return nil
+	}
+	scores := map[string]float64{}
+	if q.regex != nil {
+		for rel := range ix.Notes {
+			scores[rel] = 0
+		}
+	} else {
+		for i, w := range q.words {
+			postings := ix.prefixPostings(w)
+			idf := math.Log(1 + float64(len(ix.Notes))/float64(1+len(postings)))
+			next := map[string]float64{}
+			for rel, n := range postings {
+				if _, ok := scores[rel]; i > 0 && !ok {
+					continue
+				}
+				next[rel] = scores[rel] + float64(n)*idf
+			}
+			scores = next
+		}
+		for rel, s := range scores {
+			scores[rel] = s / (1 + math.Log(1+float64(ix.Notes[rel].Words)))
+		}
+	}
+
+	// best candidates first, only those are read
+	candidates := make([]string, 0, len(scores))
+	for rel := range scores {
+		candidates = append(candidates, rel)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return ix.Notes[a].ModTime > ix.Notes[b].ModTime
+	})
+
+	var matches []noteMatch
+	for _, rel := range candidates {
+		if len(matches) >= maxNoteSearchResults {
+			break
+		}
+		path := filepath.Join(ix.Root, filepath.FromSlash(rel))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		m, ok := q.matchNote(string(data))
+		if !ok {
+			continue
+		}
+		m.Path, m.Rel, m.ModTime = path, rel, ix.Notes[rel].ModTime
+		m.Score += scores[rel]
+		matches = append(matches, m)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ModTime > matches[j].ModTime
+	})
+	return matches
+}
+
+// checks the text against phrases / regex and picks the line with the most hits
+// as snippet
+func (q notesQuery) matchNote(text string) (noteMatch, bool) {
+	var best noteMatch
+	bestHits := 0
+	total := 0
+	phraseSeen := make([]bool, len(q.phrases))
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(strings.ReplaceAll(line, "\t", "    "), "\r")
+		lower := strings.ToLower(line)
+		var spans [][2]int
+		if q.regex != nil {
+			for _, loc := range q.regex.FindAllStringIndex(line, -1) {
+				if loc[1] > loc[0] {
+					spans = append(spans, [2]int{loc[0], loc[1]})
+				}
+			}
+		} else {
+			for j, p := range q.phrases {
+				found := substringSpans(lower, p)
+				if len(found) > 0 {
+					phraseSeen[j] = true
+				}
+				spans = append(spans, found...)
+			}
+			for _, w := range q.words {
+				spans = append(spans, substringSpans(lower, w)...)
+			}
+		}
+		total += len(spans)
+		if len(spans) > bestHits {
+			bestHits = len(spans)
+			sort.Slice(spans, func(a, b int) bool { return spans[a][0] < spans[b][0] })
+			best = noteMatch{Line: i, Snippet: line, Col: spans[0][0], Len: spans[0][1] - spans[0][0]}
+			best.Positions = nil
+			for _, s := range spans {
+				for p := s[0]; p < s[1]; p++ {
+					best.Positions = append(best.Positions, p)
+				}
+			}
+		}
+	}
+	for _, seen := range phraseSeen {
+		if !seen {
+			return noteMatch{}, false
+		}
+	}
+	if total == 0 {
+		return noteMatch{}, false
+	}
+	if q.regex != nil {
+		best.Score = float64(total)
+	} else {
+		best.Score = float64(len(q.phrases)) * 2
+	}
+	return best, true
+}
+
+// byte ranges of every occurrence of sub in s
+func substringSpans(s, sub string) [][2]int {
+	var spans [][2]int
+	if sub == "" {
+		return nil
+	}
+	for start := 0; ; {
+		i := strings.Index(s[start:], sub)
+		if i == -1 {
+			return spans
+		}
+		spans = append(spans, [2]int{start + i, start + i + len(sub)})
+		start += i + len(sub)
+	}
+}
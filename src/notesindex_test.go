@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestParseNotesQuery(t *testing.T) {
+	tests := []struct {
+		in      string
+		words   []string
+		phrases []string
+		regex   bool
+	}{
+		{"", nil, nil, false},
+		{"meet Notes", []string{"meet", "notes"}, nil, false},
+		{"a-b", []string{"a", "b"}, nil, false},
+		{`"Weekly Sync" budget`, []string{"weekly", "sync", "budget"}, []string{"weekly sync"}, false},
+		{`budget "open phrase`, []string{"budget", "open", "phrase"}, []string{"open phrase"}, false},
+		{`"" x`, []string{"x"}, nil, false},
+		{`/todo\s+\d/`, nil, nil, true},
+		{"/", nil, nil, false},
+	}
+	for _, tt := range tests {
+		q, err := parseNotesQuery(tt.in)
+		if err != nil {
+			t.Errorf("parseNotesQuery(%q): %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(q.words, tt.words) || !slices.Equal(q.phrases, tt.phrases) || (q.regex != nil) != tt.regex {
+			t.Errorf("parseNotesQuery(%q) = words %q, phrases %q, regex %v, want %q, %q, %v",
+				tt.in, q.words, q.phrases, q.regex, tt.words, tt.phrases, tt.regex)
+		}
+	}
+
+	if _, err := parseNotesQuery("/(/"); err == nil {
+		t.Errorf("parseNotesQuery(/(/) did not fail")
+	}
+}
+
+func TestMatchNote(t *testing.T) {
+	tests := []struct {
+		query, text string
+		ok          bool
+		line, col   int
+		length      int
+	}{
+		{"budget", "intro\nthe budget and budgets\nend", true, 1, 4, 6},
+		{"missing", "intro\nthe budget", false, 0, 0, 0},
+		// the line with the most hits is the snippet
+		{`"weekly sync" notes`, "notes\nour Weekly Sync notes", true, 1, 4, 11},
+		// a phrase has to appear as written
+		{`"weekly sync"`, "weekly\nsync", false, 0, 0, 0},
+		{`/t[o0]do/`, "nothing\nTODO: x", true, 1, 0, 4},
+		{`/^x$/`, "xx\ny", false, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		q, err := parseNotesQuery(tt.query)
+		if err != nil {
+			t.Fatalf("parseNotesQuery(%q): %v", tt.query, err)
+		}
+		m, ok := q.matchNote(tt.text)
+		if ok != tt.ok || m.Line != tt.line || m.Col != tt.col || m.Len != tt.length {
+			t.Errorf("matchNote(%q) = %v line %d col %d len %d, want %v line %d col %d len %d",
+				tt.query, ok, m.Line, m.Col, m.Len, tt.ok, tt.line, tt.col, tt.length)
+		}
+	}
+}
+
+func TestNotesSearch(t *testing.T) {
+	root := t.TempDir()
+	ix := &notesIndex{Root: root, Notes: map[string]*indexedNote{}, Terms: map[string]map[string]int{}}
+	for rel, text := range map[string]string{
+		"a.txt":     "Meeting with the budget team",
+		"b.txt":     "meet the team",
+		"sub/c.txt": "unrelated, #budget later",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ix.add(rel, 0, text)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// words match word starts
+		{"meet", []string{"a.txt", "b.txt"}},
+		{"eting", nil},
+		// every word has to be in the note
+		{"meet budget", []string{"a.txt"}},
+		{"budget", []string{"a.txt", "sub/c.txt"}},
+		// phrases have to appear as written, not only their words
+		{`"the team"`, []string{"b.txt"}},
+		{`"budget team" meet`, []string{"a.txt"}},
+		{`/unrel.ted/`, []string{"sub/c.txt"}},
+	}
+	for _, tt := range tests {
+		q, err := parseNotesQuery(tt.query)
+		if err != nil {
+			t.Fatalf("parseNotesQuery(%q): %v", tt.query, err)
+		}
+		var got []string
+		for _, m := range ix.search(q) {
+			got = append(got, m.Rel)
+		}
+		sort.Strings(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
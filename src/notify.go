@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Notifications. notify() keeps a message in the history and shows it as a toast
+// in the bottom right corner for a few seconds, errors stay longest. A click
+// dismisses a toast. The history is shown by the Messages panel (View: Messages),
+// editorStatus stays for short feedback that does not need to be kept.
+
+type NoticeLevel int
+
+const (
+	NoticeInfo NoticeLevel = iota
+	NoticeWarning
+	NoticeError
+)
+
+type Notice struct {
+	Level NoticeLevel
+	Text  string
+	Time  time.Time
+	Count int // the same message repeated right after itself
+}
+
+const noticeHistoryMax = 500
+const toastWidth = 360
+const toastMaxVisible = 4
+
+var notices []*Notice
+
+type toast struct {
+	notice  *Notice
+	expires time.Time
+}
+
+var toasts []*toast
+
+func (l NoticeLevel) String() string {
+	switch l {
+	case NoticeWarning:
+		return "warning"
+	case NoticeError:
+		return "error"
+	}
+	return "info"
+}
+
+func (l NoticeLevel) color() rl.Color {
+	switch l {
+	case NoticeWarning:
+		return ModernWarning
+	case NoticeError:
+		return ModernDanger
+	}
+	return ModernAccent
+}
+
+func (l NoticeLevel) duration() time.Duration {
+	switch l {
+	case NoticeWarning:
+		return 5 * time.Second
+	case NoticeError:
+		return 8 * time.Second
+	}
+	return 3 * time.Second
+}
+
+func notify(level NoticeLevel, text string) {
+	now := time.Now()
+	if n := len(notices); n > 0 && notices[n-1].Text == text && notices[n-1].Level == level {
+		last := notices[n-1]
+		last.Count++
+		last.Time = now
+		showToast(last)
+		return
+	}
+	notice := &Notice{Level: level, Text: text, Time: now, Count: 1}
+	notices = append(notices, notice)
+	if len(notices) > noticeHistoryMax {
+		notices = notices[len(notices)-noticeHistoryMax:]
+	}
+	showToast(notice)
+}
+
+func notifyInfo(format string, args ...any) {
+	notify(NoticeInfo, fmt.Sprintf(format, args...))
+}
+
+func notifyWarning(format string, args ...any) {
+	notify(NoticeWarning, fmt.Sprintf(format, args...))
+}
+
+func notifyError(format string, args ...any) {
+	notify(NoticeError, fmt.Sprintf(format, args...))
+}
+
+// (re)shows the notice as the newest toast
+func showToast(n *Notice) {
+	for i, t := range toasts {
+		if t.notice == n {
+			toasts = append(toasts[:i], toasts[i+1:]...)
+			break
+		}
+	}
+	toasts = append(toasts, &toast{notice: n, expires: time.Now().Add(n.Level.duration())})
+}
+
+// ------------------------------------------------------------------------------------
+
+// toasts on screen, newest at the bottom, with their rectangles
+func visibleToasts() ([]*toast, []rl.Rectangle) {
+	now := time.Now()
+	live := toasts[:0]
+	for _, t := range toasts {
+		if now.Before(t.expires) {
+			live = append(live, t)
+		}
+	}
+	toasts = live
+
+	shown := toasts[max(0, len(toasts)-toastMaxVisible):]
+	rects := make([]rl.Rectangle, len(shown))
+	w := min(toastWidth, windowWidth-20)
+	y := windowHeight - editorBottomPadding - 10
+	for i := len(shown) - 1; i >= 0; i-- {
+		h := toastHeight(shown[i].notice, w)
+		y -= h
+		rects[i] = rl.NewRectangle(float32(windowWidth-w-10), float32(y), float32(w), float32(h))
+		y -= 8
+	}
+	return shown, rects
+}
+
+func toastLines(n *Notice, w int) []string {
+	lines := wrapText(n.Text, (w-28)/CHAR_IMAGE_WIDTH)
+	if len(lines) > 4 {
+		lines = append(lines[:3], clampText(lines[3]+" ...", (w-28)/CHAR_IMAGE_WIDTH))
+	}
+	return lines
+}
+
+func toastHeight(n *Notice, w int) int {
+	return 16 + len(toastLines(n, w))*18
+}
+
+// a click on a toast dismisses it, true when the click was taken
+func handleToastInput() bool {
+	if !rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+		return false
+	}
+	mouse := rl.GetMousePosition()
+	shown, rects := visibleToasts()
+	for i, r := range rects {
+		if rl.CheckCollisionPointRec(mouse, r) {
+			shown[i].expires = time.Time{}
+			return true
+		}
+	}
+	return false
+}
+
+func drawToasts() {
+	shown, rects := visibleToasts()
+	for i, t := range shown {
+		r := rects[i]
+		x, y, w, h := int32(r.X), int32(r.Y), int32(r.Width), int32(r.Height)
+		drawShadow(r.X, r.Y, r.Width, r.Height, 4, 8)
+		rl.DrawRectangle(x, y, w, h, ModernMedium)
+		rl.DrawRectangleLines(x, y, w, h, ModernBorder)
+		rl.DrawRectangle(x, y, 4, h, t.notice.Level.color())
+
+		lines := toastLines(t.notice, int(w))
+		if t.notice.Count > 1 && len(lines) > 0 {
+			lines[0] = fmt.Sprintf("(%d) %s", t.notice.Count, lines[0])
+		}
+		for j, line := range lines {
+			DrawText(line, int(x)+16, int(y)+8+j*18, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+		}
+	}
+}
+
+// ------------------------------------------------------------------------------------
+
+// the Messages panel on the modal stack, the history newest first
+type messagesPanel struct {
+	scroll int
+}
+
+var messagesModal = &messagesPanel{}
+
+const messagesRowHeight = 20
+
+func openMessagesPanel() {
+	messagesModal.scroll = 0
+	pushModal(messagesModal)
+}
+
+func (m *messagesPanel) drawModal(top bool) {
+	w := min(760, windowWidth-40)
+	h := min(480, windowHeight-40)
+	x := (windowWidth - w) / 2
+	y := (windowHeight - h) / 2
+
+	rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), ModernOverlay)
+	drawShadow(float32(x), float32(y), float32(w), float32(h), 6, 12)
+	rl.DrawRectangle(int32(x), int32(y), int32(w), int32(h), ModernMedium)
+	rl.DrawRectangleLines(int32(x), int32(y), int32(w), int32(h), ModernBorder)
+	rl.DrawRectangle(int32(x), int32(y), int32(w), 44, ModernDark)
+	DrawText(fmt.Sprintf("Messages (%d)", len(notices)), x+20, y+16, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+
+	listY := y + 44 + 8
+	visible := max(1, (h-44-8-52)/messagesRowHeight)
+	maxScroll := max(0, len(notices)-visible)
+
+	if top {
+		if wheel := rl.GetMouseWheelMove(); wheel != 0 {
+			m.scroll -= int(wheel * 3)
+		}
+		switch {
+		case rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown):
+			m.scroll++
+		case rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp):
+			m.scroll--
+		case rl.IsKeyPressed(rl.KeyPageDown):
+			m.scroll += visible
+		case rl.IsKeyPressed(rl.KeyPageUp):
+			m.scroll -= visible
+		case rl.IsKeyPressed(rl.KeyHome):
+			m.scroll = 0
+		case rl.IsKeyPressed(rl.KeyEnd):
+			m.scroll = maxScroll
+		case rl.IsKeyPressed(rl.KeyEscape):
+			closeModal(m)
+			return
+		}
+	}
+	m.scroll = max(0, min(m.scroll, maxScroll))
+
+	if len(notices) == 0 {
+		DrawText("No messages", x+20, listY+4, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+	}
+	textChars := (w - 40 - 22*CHAR_IMAGE_WIDTH) / CHAR_IMAGE_WIDTH
+	for i := 0; i < visible; i++ {
+		idx := len(notices) - 1 - (m.scroll + i)
+		if idx < 0 {
+			break
+		}
+		n := notices[idx]
+		ry := listY + i*messagesRowHeight
+		rl.DrawRectangle(int32(x+12), int32(ry+3), 4, messagesRowHeight-6, n.Level.color())
+		DrawText(n.Time.Format("15:04:05"), x+24, ry+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+
+		levelColor := "ui.textDim"
+		switch n.Level {
+		case NoticeWarning:
+			levelColor = "ui.warning"
+		case NoticeError:
+			levelColor = "ui.danger"
+		}
+		DrawText(n.Level.String(), x+24+10*CHAR_IMAGE_WIDTH, ry+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, levelColor)
+
+		text := n.Text
+		if n.Count > 1 {
+			text = fmt.Sprintf("(%d) %s", n.Count, text)
+		}
+		DrawText(clampText(text, textChars), x+24+19*CHAR_IMAGE_WIDTH, ry+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+	}
+
+	// scroll bar
+	if maxScroll > 0 {
+		trackH := visible * messagesRowHeight
+		thumbH := max(20, trackH*visible/len(notices))
+		thumbY := listY + (trackH-thumbH)*m.scroll/maxScroll
+		rl.DrawRectangle(int32(x+w-12), int32(listY), 6, int32(trackH), ScrollTrackColor)
+		rl.DrawRectangle(int32(x+w-12), int32(thumbY), 6, int32(thumbH), ScrollThumbColor)
+	}
+
+	by := int32(y + h - 44)
+	if DrawModernButton("Close", int32(x+w-100), by, 80, 32, ModernText, ModernDanger, ModernLight, ModernMedium, true) && top {
+		closeModal(m)
+		return
+	}
+	if DrawModernButton("Clear", int32(x+w-190), by, 80, 32, ModernText, ModernAccent, ModernLight, ModernMedium, true) && top {
+		notices = nil
+		toasts = nil
+		m.scroll = 0
+	}
+}
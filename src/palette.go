@@ -0,0 +1,162 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Command palette (Ctrl+Shift+P): a filter box over the list of all commands,
+// fuzzy matched on the title. Up/Down move, Enter runs, Esc closes.
+
+const paletteRowHeight = 24
+const paletteMaxRows = 12
+
+// the palette on the modal stack
+type commandPalette struct{}
+
+var commandPaletteModal = &commandPalette{}
+
+func (*commandPalette) drawModal(top bool) {
+	if top {
+		drawCommandPalette(ui)
+	}
+}
+
+func openCommandPalette() {
+	pushModal(commandPaletteModal)
+	ui.PaletteSelected = 0
+	ui.PaletteScroll = 0
+	ui.InputBoxes = []*InputBox{
+		{
+			Rect:     rl.NewRectangle(0, 0, 0, 32),
+			Text:     "",
+			MaxChars: 64,
+		},
+	}
+}
+
+func closeCommandPalette() {
+	closeModal(commandPaletteModal)
+	ui.InputBoxes = nil
+}
+
+// commands matching the filter, best match first
+func paletteMatches(filter string) ([]*Command, []fuzzyMatch) {
+	titles := make([]string, len(commands))
+	for i, c := range commands {
+		titles[i] = c.Title
+	}
+	matches := fuzzyFilter(filter, titles)
+	result := make([]*Command, len(matches))
+	for i, m := range matches {
+		result[i] = commands[m.Index]
+	}
+	return result, matches
+}
+
+func drawCommandPalette(ui *UIState) {
+	if len(ui.InputBoxes) == 0 {
+		closeCommandPalette()
+		return
+	}
+	box := ui.InputBoxes[0]
+	before := box.Text
+	box.HandleInput()
+	if box.Text != before {
+		ui.PaletteSelected = 0
+		ui.PaletteScroll = 0
+	}
+
+	found, matches := paletteMatches(box.Text)
+
+	// keyboard
+	if rl.IsKeyPressed(rl.KeyEscape) {
+		closeCommandPalette()
+		return
+	}
+	if (rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown)) && ui.PaletteSelected < len(found)-1 {
+		ui.PaletteSelected++
+	}
+	if (rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp)) && ui.PaletteSelected > 0 {
+		ui.PaletteSelected--
+	}
+	if rl.IsKeyPressed(rl.KeyEnter) && ui.PaletteSelected < len(found) {
+		closeCommandPalette()
+		found[ui.PaletteSelected].Run()
+		return
+	}
+
+	visible := min(len(found), paletteMaxRows)
+	if ui.PaletteSelected < ui.PaletteScroll {
+		ui.PaletteScroll = ui.PaletteSelected
+	}
+	if ui.PaletteSelected >= ui.PaletteScroll+paletteMaxRows {
+		ui.PaletteScroll = ui.PaletteSelected - paletteMaxRows + 1
+	}
+	if wheel := rl.GetMouseWheelMove(); wheel != 0 {
+		ui.PaletteScroll -= int(wheel)
+		ui.PaletteScroll = max(0, min(ui.PaletteScroll, len(found)-visible))
+	}
+
+	// layout, centered at the top of the window
+	w := int32(min(600, windowWidth-40))
+	h := int32(56 + max(1, visible)*paletteRowHeight)
+	x := int32(windowWidth)/2 - w/2
+	y := int32(editorTopPadding + 20)
+
+	rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), ModernOverlay)
+	drawShadow(float32(x), float32(y), float32(w), float32(h), 4, 8)
+	rl.DrawRectangle(x, y, w, h, ModernMedium)
+	rl.DrawRectangleLines(x, y, w, h, ModernBorder)
+
+	box.Rect = rl.NewRectangle(float32(x+8), float32(y+8), float32(w-16), 32)
+	box.Draw()
+
+	if len(found) == 0 {
+		DrawText("No matching commands", int(x)+16, int(y)+56, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		return
+	}
+
+	mouseX, mouseY := rl.GetMouseX(), rl.GetMouseY()
+	for i := 0; i < visible; i++ {
+		idx := ui.PaletteScroll + i
+		if idx >= len(found) {
+			break
+		}
+		c := found[idx]
+		rowY := y + 48 + int32(i*paletteRowHeight)
+		hover := mouseX >= x && mouseX < x+w && mouseY >= rowY && mouseY < rowY+paletteRowHeight
+
+		if idx == ui.PaletteSelected {
+			rl.DrawRectangle(x+4, rowY, w-8, paletteRowHeight, ModernLight)
+		} else if hover {
+			rl.DrawRectangle(x+4, rowY, w-8, paletteRowHeight, ModernDarkButton)
+		}
+
+		drawMatchedText(c.Title, matches[idx].Positions, int(x)+16, int(rowY)+5, len(c.Title))
+
+		if binding := commandBinding(c.ID); binding != "" {
+			bx := int(x+w) - 16 - len(binding)*CHAR_IMAGE_WIDTH
+			DrawText(binding, bx, int(rowY)+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		}
+
+		if hover && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+			closeCommandPalette()
+			c.Run()
+			return
+		}
+	}
+}
+
+// text with the fuzzy matched letters in the accent color, cut to maxChars
+func drawMatchedText(text string, positions []int, x, y, maxChars int) {
+	for j := 0; j < len(text) && j < maxChars; j++ {
+		color := "ui.text"
+		for len(positions) > 0 && positions[0] < j {
+			positions = positions[1:]
+		}
+		if len(positions) > 0 && positions[0] == j {
+			color = "ui.accent"
+		}
+		DrawCharacter(text[j], x+j*CHAR_IMAGE_WIDTH, y, rl.DrawPixel, color)
+	}
+}
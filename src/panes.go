@@ -0,0 +1,405 @@
+package main
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Editor panes. The editor area is a tree of splits with a pane in every leaf. A
+// pane has its own cursor, selection, scroll offsets, viewport and wrap layout and
+// shows a buffer, several panes can show the same one. The globals the editing code
+// works on (textGrid, usedRows, cursor, selection, ...) always hold the state of
+// the active pane: activate() stores them into the buffer they came from and loads
+// the ones of the next pane. The focused pane gets the keyboard, while drawing every
+// pane is activated in turn.
+
+const paneDividerSize = 4
+const paneMinWidth = 120
+const paneMinHeight = 3 * CHAR_IMAGE_HEIGHT
+
+// a document, the text and everything cached about it
+type Buffer struct {
+	file        string
+	grid        [][]byte
+	rows        int
+	cols        int
+	used        int
+	undo        []EditorSnapshot
+	redo        []EditorSnapshot
+	highlighter *Highlighter
+	savedLines  map[uint64]bool
+	modified    modifiedLinesCache
+	search      searchLinesCache
+}
+
+type Pane struct {
+	buf       *Buffer
+	cursor    Cursor
+	selection Selection
+	scrollX   int
+	scrollY   int
+	wrap      *wrapLayout
+
+	// viewport on screen, set by layoutPanes
+	x, y, w, h int
+}
+
+// a split with two children, or a leaf with a pane
+type paneNode struct {
+	pane       *Pane
+	sideBySide bool    // children left and right, else top and bottom
+	ratio      float32 // share of the first child
+	first      *paneNode
+	second     *paneNode
+	x, y, w, h int
+}
+
+var activePane = &Pane{buf: &Buffer{highlighter: highlighter}, wrap: wrap}
+var focusedPane = activePane
+var paneRoot = &paneNode{pane: activePane}
+
+var paneDrag struct {
+	node *paneNode // split whose divider is being dragged
+}
+
+// unsaved pane close that needs a second Ctrl+W
+var paneClosePending *Pane
+
+// saves the globals of the active pane into its buffer
+func (b *Buffer) store() {
+	b.file = currentFile
+	b.grid, b.rows, b.cols, b.used = textGrid, editorRows, editorCols, usedRows
+	b.undo, b.redo = undoStack, redoStack
+	b.highlighter = highlighter
+	b.savedLines, b.modified, b.search = savedLines, modifiedCache, searchCache
+}
+
+func (b *Buffer) load() {
+	currentFile = b.file
+	textGrid, editorRows, editorCols, usedRows = b.grid, b.rows, b.cols, b.used
+	undoStack, redoStack = b.undo, b.redo
+	highlighter = b.highlighter
+	savedLines, modifiedCache, searchCache = b.savedLines, b.modified, b.search
+}
+
+// makes p the pane the editing globals refer to
+func (p *Pane) activate() {
+	if p == activePane {
+		return
+	}
+	activePane.buf.store()
+	activePane = p
+	p.buf.load()
+	cursor = &p.cursor
+	selection = &p.selection
+	wrap = p.wrap
+
+	// the buffer may have been edited in another pane meanwhile
+	clampCursor()
+	selection.clamp()
+}
+
+// runs fn with p active, then goes back to the pane that was active before
+func withPane(p *Pane, fn func()) {
+	prev := activePane
+	if p != nil {
+		p.activate()
+	}
+	fn()
+	prev.activate()
+}
+
+func focusPane(p *Pane) {
+	if p == nil || p == focusedPane {
+		return
+	}
+	p.activate()
+	focusedPane = p
+	paneClosePending = nil
+}
+
+// leaves in screen order, left to right and top to bottom
+func panes() []*Pane {
+	var list []*Pane
+	var walk func(n *paneNode)
+	walk = func(n *paneNode) {
+		if n.pane != nil {
+			list = append(list, n.pane)
+			return
+		}
+		walk(n.first)
+		walk(n.second)
+	}
+	walk(paneRoot)
+	return list
+}
+
+func bufferShared(b *Buffer) bool {
+	count := 0
+	for _, p := range panes() {
+		if p.buf == b {
+			count++
+		}
+	}
+	return count > 1
+}
+
+// gives the active pane a buffer of its own before its text is replaced, the
+// other panes keep showing the old one
+func detachSharedBuffer() {
+	if !bufferShared(activePane.buf) {
+		return
+	}
+	activePane.buf.store()
+	activePane.buf = &Buffer{highlighter: &Highlighter{}}
+	highlighter = activePane.buf.highlighter
+	undoStack, redoStack = nil, nil
+	savedLines = map[uint64]bool{}
+	modifiedCache = modifiedLinesCache{version: -1}
+	searchCache = searchLinesCache{}
+	activePane.wrap.dirty = true
+}
+
+// runs fn once for every open buffer with a pane showing it active
+func forEachBuffer(fn func()) {
+	seen := map[*Buffer]bool{}
+	for _, p := range panes() {
+		if seen[p.buf] {
+			continue
+		}
+		seen[p.buf] = true
+		withPane(p, fn)
+	}
+}
+
+// ------------------------------------------------------------------------------------
+
+// area the panes share, right of the sidebar and between menu and status bar
+func layoutPanes() {
+	top := editorTopPadding
+	paneRoot.layout(editorLeft(), top, windowWidth-editorLeft(), windowHeight-top-editorBottomPadding)
+}
+
+func (n *paneNode) layout(x, y, w, h int) {
+	n.x, n.y, n.w, n.h = x, y, w, h
+	if n.pane != nil {
+		n.pane.x, n.pane.y, n.pane.w, n.pane.h = x, y, w, h
+		return
+	}
+	if n.sideBySide {
+		first := splitSize(w, n.ratio, paneMinWidth)
+		n.first.layout(x, y, first, h)
+		n.second.layout(x+first+paneDividerSize, y, w-first-paneDividerSize, h)
+	} else {
+		first := splitSize(h, n.ratio, paneMinHeight)
+		n.first.layout(x, y, w, first)
+		n.second.layout(x, y+first+paneDividerSize, w, h-first-paneDividerSize)
+	}
+}
+
+// size of the first child, both children keep at least minSize when there is room
+func splitSize(total int, ratio float32, minSize int) int {
+	size := int(float32(total-paneDividerSize) * ratio)
+	if total-paneDividerSize < 2*minSize {
+		return max(0, (total-paneDividerSize)/2)
+	}
+	return max(minSize, min(size, total-paneDividerSize-minSize))
+}
+
+// divider rectangle of a split
+func (n *paneNode) divider() (int, int, int, int) {
+	if n.sideBySide {
+		return n.first.x + n.first.w, n.y, paneDividerSize, n.h
+	}
+	return n.x, n.first.y + n.first.h, n.w, paneDividerSize
+}
+
+func (n *paneNode) find(p *Pane) (node, parent *paneNode) {
+	if n.pane == p {
+		return n, nil
+	}
+	if n.pane != nil {
+		return nil, nil
+	}
+	for _, c := range []*paneNode{n.first, n.second} {
+		if found, parent := c.find(p); found != nil {
+			if parent == nil {
+				parent = n
+			}
+			return found, parent
+		}
+	}
+	return nil, nil
+}
+
+// ------------------------------------------------------------------------------------
+
+// splits the focused pane, the new pane shows the same buffer at the same place
+func splitPane(sideBySide bool) {
+	node, _ := paneRoot.find(focusedPane)
+	old := focusedPane
+	p := &Pane{
+		buf:     old.buf,
+		cursor:  old.cursor,
+		scrollX: old.scrollX,
+		scrollY: old.scrollY,
+		wrap:    &wrapLayout{dirty: true},
+	}
+	*node = paneNode{
+		sideBySide: sideBySide,
+		ratio:      0.5,
+		first:      &paneNode{pane: old},
+		second:     &paneNode{pane: p},
+	}
+	layoutPanes()
+	focusPane(p)
+	ensureCursorVisible(cursor)
+}
+
+func splitPaneRight() { splitPane(true) }
+
+func splitPaneDown() { splitPane(false) }
+
+// closes the focused pane, its sibling takes the space. A buffer with unsaved
+// changes that is not shown anywhere else needs a second Ctrl+W.
+func closePane() {
+	node, parent := paneRoot.find(focusedPane)
+	if parent == nil {
+		editorStatus = "Only one pane open"
+		return
+	}
+	if !bufferShared(focusedPane.buf) && len(modifiedLines()) > 0 && paneClosePending != focusedPane {
+		paneClosePending = focusedPane
+		editorStatus = "Unsaved changes in " + currentFile + ", close again to discard them"
+		return
+	}
+
+	sibling := parent.first
+	if sibling == node {
+		sibling = parent.second
+	}
+	*parent = *sibling
+	layoutPanes()
+	focusPane(firstPane(parent))
+	ensureCursorVisible(cursor)
+}
+
+func firstPane(n *paneNode) *Pane {
+	for n.pane == nil {
+		n = n.first
+	}
+	return n.pane
+}
+
+// moves the focus to the next (+1) or previous (-1) pane in screen order
+func cyclePaneFocus(dir int) {
+	list := panes()
+	for i, p := range list {
+		if p == focusedPane {
+			focusPane(list[(i+dir+len(list))%len(list)])
+			return
+		}
+	}
+}
+
+func focusNextPane() { cyclePaneFocus(1) }
+
+func focusPreviousPane() { cyclePaneFocus(-1) }
+
+// pane under the screen position, nil over a divider or outside the editor
+func paneAt(x, y int) *Pane {
+	for _, p := range panes() {
+		if x >= p.x && x < p.x+p.w && y >= p.y && y < p.y+p.h {
+			return p
+		}
+	}
+	return nil
+}
+
+// split whose divider is under the screen position
+func dividerAt(x, y int) *paneNode {
+	var found *paneNode
+	var walk func(n *paneNode)
+	walk = func(n *paneNode) {
+		if n.pane != nil || found != nil {
+			return
+		}
+		dx, dy, dw, dh := n.divider()
+		if x >= dx-1 && x < dx+dw+1 && y >= dy && y < dy+dh {
+			found = n
+			return
+		}
+		walk(n.first)
+		walk(n.second)
+	}
+	walk(paneRoot)
+	return found
+}
+
+// divider dragging and focus on click, true while a divider is dragged so the
+// click does not reach the text
+func handlePaneInput() bool {
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+
+	if paneDrag.node != nil {
+		n := paneDrag.node
+		if !rl.IsMouseButtonDown(rl.MouseLeftButton) {
+			paneDrag.node = nil
+			rl.SetMouseCursor(rl.MouseCursorDefault)
+			ensureCursorVisible(cursor)
+			return true
+		}
+		if n.sideBySide {
+			n.ratio = float32(mouseX-n.x) / float32(n.w-paneDividerSize)
+		} else {
+			n.ratio = float32(mouseY-n.y) / float32(n.h-paneDividerSize)
+		}
+		n.ratio = max(0.05, min(n.ratio, 0.95))
+		layoutPanes()
+		return true
+	}
+
+	if n := dividerAt(mouseX, mouseY); n != nil {
+		if n.sideBySide {
+			rl.SetMouseCursor(rl.MouseCursorResizeEW)
+		} else {
+			rl.SetMouseCursor(rl.MouseCursorResizeNS)
+		}
+		if rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+			paneDrag.node = n
+			return true
+		}
+	} else if len(panes()) > 1 && !mouseOverSidebar() {
+		rl.SetMouseCursor(rl.MouseCursorDefault)
+	}
+
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) || rl.IsMouseButtonPressed(rl.MouseRightButton) ||
+		rl.IsMouseButtonPressed(rl.MouseMiddleButton) {
+		focusPane(paneAt(mouseX, mouseY))
+	}
+	return false
+}
+
+// dividers between the panes and a marker on the focused one
+func drawPaneDividers() {
+	if paneRoot.pane != nil {
+		return
+	}
+	var walk func(n *paneNode)
+	walk = func(n *paneNode) {
+		if n.pane != nil {
+			return
+		}
+		x, y, w, h := n.divider()
+		color := ModernBorder
+		if n == paneDrag.node {
+			color = ModernAccent
+		}
+		rl.DrawRectangle(int32(x), int32(y), int32(w), int32(h), color)
+		walk(n.first)
+		walk(n.second)
+	}
+	walk(paneRoot)
+
+	p := focusedPane
+	rl.DrawRectangle(int32(p.x), int32(p.y), int32(p.w), 2, ModernAccent)
+}
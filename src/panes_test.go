@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestActivateClampsSelection(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		pick   func()
+		shrink int // rows the other pane leaves in the buffer, 0 keeps them
+		want   string
+	}{
+		{"select all", "one\ntwo\nthree", selectAll, 0, "one\ntwo\nthree"},
+		{"select all, buffer shrunk", "one\ntwo\nthree", selectAll, 2, "one\ntwo"},
+		{"line inside the buffer", "one\ntwo\nthree", func() { selectLine(0) }, 2, "one\n"},
+	}
+	for _, tt := range tests {
+		first := activePane
+		second := &Pane{buf: first.buf, wrap: &wrapLayout{dirty: true}}
+		loadStringIntoTextGrid(tt.text)
+		tt.pick()
+
+		second.activate()
+		if tt.shrink > 0 {
+			for x := range textGrid[tt.shrink-1] {
+				if textGrid[tt.shrink-1][x] == '\n' {
+					textGrid[tt.shrink-1][x] = 0
+				}
+			}
+			usedRows = tt.shrink
+		}
+		first.activate()
+
+		if !selection.Active {
+			t.Errorf("%s: selection was reset", tt.name)
+			continue
+		}
+		if got := getSelectedText(); got != tt.want {
+			t.Errorf("%s: selected %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Quick Open (Ctrl+P): fuzzy search over every file of the project. The file list
+// is built by a background walk that skips .gitignore'd paths and the "ignore"
+// patterns of the config. Folders and .gitignore files seen by the walk are
+// polled for changes and the index is rebuilt when one of them changes.
+
+const maxIndexedFiles = 100000
+const maxQuickOpenResults = 500
+const quickOpenRowHeight = 22
+
+type fileIndex struct {
+	mu       sync.Mutex
+	root     string
+	files    []string             // slash separated, relative to root
+	watched  map[string]time.Time // folders and .gitignore files with their mod time
+	building bool
+	ready    bool
+	version  int // bumped on every rebuild
+
+	lastCheck time.Time
+	checking  bool
+}
+
+var projectIndex = &fileIndex{root: "."}
+
+// starts a background rebuild unless one is already running
+func (idx *fileIndex) refresh() {
+	idx.mu.Lock()
+	if idx.building {
+		idx.mu.Unlock()
+		return
+	}
+	idx.building = true
+	root := idx.root
+	idx.mu.Unlock()
+
+	patterns := append([]string(nil), settings.Ignore...)
+	go func() {
+		files, watched := walkProject(root, patterns)
+
+		idx.mu.Lock()
+		idx.files = files
+		idx.watched = watched
+		idx.building = false
+		idx.ready = true
+		idx.version++
+		idx.lastCheck = time.Now()
+		idx.mu.Unlock()
+	}()
+}
+
+func walkProject(root string, patterns []string) ([]string, map[string]time.Time) {
+	ignore := newIgnoreSet(patterns)
+	var files []string
+	watched := map[string]time.Time{}
+
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// unreadable folder, skip it but keep walking
+			return nil
+		}
+		rel := relSlash(root, p)
+
+		if d.IsDir() {
+			base := rel
+			if rel == "." {
+				base = ""
+			} else if ignore.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			ignore.addFile(p, base)
+			if info, err := d.Info(); err == nil {
+				watched[p] = info.ModTime()
+			}
+			if info, err := os.Stat(filepath.Join(p, ".gitignore")); err == nil {
+				watched[filepath.Join(p, ".gitignore")] = info.ModTime()
+			}
+			return nil
+		}
+
+		if ignore.ignored(rel, false) {
+			return nil
+		}
+		files = append(files, rel)
+		if len(files) >= maxIndexedFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return files, watched
+}
+
+// current file list, building is true while a rebuild runs and ready once the
+// first walk finished
+func (idx *fileIndex) snapshot() (files []string, version int, building, ready bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.files, idx.version, idx.building, idx.ready
+}
+
+// called every frame, every two seconds the watched paths are checked in the
+// background and the index is rebuilt if any of them changed
+func (idx *fileIndex) check() {
+	idx.mu.Lock()
+	if !idx.ready || idx.building || idx.checking || time.Since(idx.lastCheck) < 2*time.Second {
+		idx.mu.Unlock()
+		return
+	}
+	idx.checking = true
+	idx.lastCheck = time.Now()
+	watched := idx.watched
+	idx.mu.Unlock()
+
+	go func() {
+		changed := false
+		for p, modTime := range watched {
+			info, err := os.Stat(p)
+			if err != nil || !info.ModTime().Equal(modTime) {
+				changed = true
+				break
+			}
+		}
+
+		idx.mu.Lock()
+		idx.checking = false
+		idx.mu.Unlock()
+		if changed {
+			idx.refresh()
+		}
+	}()
+}
+
+// fuzzy score of a path. Without a "/" in the pattern the file name is tried first
+// and wins over matches spread across folders, with "/" every part of the pattern
+// has to match a later path segment ("src/ui" finds src/ui.go).
+func pathMatch(pattern, p string) (int, []int, bool) {
+	pattern = strings.Trim(pattern, "/ ")
+	if pattern == "" {
+		return 0, nil, true
+	}
+	depth := strings.Count(p, "/")
+
+	if !strings.Contains(pattern, "/") {
+		base := path.Base(p)
+		offset := len(p) - len(base)
+		best, bestPos, found := 0, []int(nil), false
+		if score, positions, ok := fuzzyScore(pattern, base); ok {
+			for i := range positions {
+				positions[i] += offset
+			}
+			best, bestPos, found = score+20, positions, true
+		}
+		if score, positions, ok := fuzzyScore(pattern, p); ok && (!found || score > best) {
+			best, bestPos, found = score, positions, true
+		}
+		return best - depth, bestPos, found
+	}
+
+	parts := strings.Split(pattern, "/")
+	segments := strings.Split(p, "/")
+	total := 0
+	var positions []int
+	offset := 0
+	seg := 0
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		matched := false
+		for ; seg < len(segments); seg++ {
+			score, pos, ok := fuzzyScore(part, segments[seg])
+			if ok {
+				for _, q := range pos {
+					positions = append(positions, offset+q)
+				}
+				total += score
+				if i == len(parts)-1 && seg == len(segments)-1 {
+					total += 20
+				}
+				matched = true
+			}
+			offset += len(segments[seg]) + 1
+			if matched {
+				seg++
+				break
+			}
+		}
+		if !matched {
+			return 0, nil, false
+		}
+	}
+	return total - depth, positions, true
+}
+
+type quickOpenResult struct {
+	Path      string
+	Score     int
+	Positions []int
+}
+
+var quickOpen struct {
+	query   string
+	version int
+	results []quickOpenResult
+
+	previewPath  string
+	previewLines [][]byte
+	previewKinds [][]TokenKind
+	previewNote  string
+}
+
+// quick open on the modal stack
+type quickOpenDialog struct{}
+
+var quickOpenModal = &quickOpenDialog{}
+
+func (*quickOpenDialog) drawModal(top bool) {
+	if top {
+		drawQuickOpen(ui)
+	}
+}
+
+func openQuickOpen() {
+	pushModal(quickOpenModal)
+	ui.QuickOpenSelected = 0
+	ui.QuickOpenScroll = 0
+	ui.InputBoxes = []*InputBox{
+		{
+			Rect:     rl.NewRectangle(0, 0, 0, 32),
+			MaxChars: 256,
+			History:  "quickOpen",
+		},
+	}
+	quickOpen.version = -1
+	quickOpen.previewPath = ""
+
+	if _, _, building, ready := projectIndex.snapshot(); !building && !ready {
+		projectIndex.refresh()
+	}
+}
+
+func closeQuickOpen() {
+	closeModal(quickOpenModal)
+	ui.InputBoxes = nil
+}
+
+func updateQuickOpenResults(query string) {
+	files, version, _, _ := projectIndex.snapshot()
+	if query == quickOpen.query && version == quickOpen.version {
+		return
+	}
+	quickOpen.query = query
+	quickOpen.version = version
+	quickOpen.results = quickOpen.results[:0]
+
+	for _, f := range files {
+		if score, positions, ok := pathMatch(query, f); ok {
+			quickOpen.results = append(quickOpen.results, quickOpenResult{f, score, positions})
+		}
+	}
+	sort.SliceStable(quickOpen.results, func(i, j int) bool {
+		a, b := quickOpen.results[i], quickOpen.results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return len(a.Path) < len(b.Path)
+	})
+	if len(quickOpen.results) > maxQuickOpenResults {
+		quickOpen.results = quickOpen.results[:maxQuickOpenResults]
+	}
+	ui.QuickOpenSelected = min(ui.QuickOpenSelected, max(0, len(quickOpen.results)-1))
+}
+
+// reads the start of the file for the preview pane, highlighted like the editor
+func loadQuickOpenPreview(p string, maxLines int) {
+	if quickOpen.previewPath == p {
+		return
+	}
+	quickOpen.previewPath = p
+	quickOpen.previewLines = nil
+	quickOpen.previewKinds = nil
+	quickOpen.previewNote = ""
+
+	f, err := os.Open(filepath.FromSlash(p))
+	if err != nil {
+		quickOpen.previewNote = err.Error()
+		return
+	}
+	defer f.Close()
+	buf := make([]byte, 32*1024)
+	n, _ := f.Read(buf)
+	buf = buf[:n]
+	if strings.ContainsRune(string(buf), 0) {
+		quickOpen.previewNote = "binary file"
+		return
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(buf), "\t", "    "), "\n")
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+	var firstLine []byte
+	if len(lines) > 0 {
+		firstLine = []byte(lines[0])
+	}
+	lang := languageForFile(p, firstLine)
+	var state []int
+	for _, line := range lines {
+		b := []byte(strings.TrimRight(line, "\r"))
+		quickOpen.previewLines = append(quickOpen.previewLines, b)
+		if lang != nil {
+			var kinds []TokenKind
+			kinds, state = lang.tokenizeLine(b, state)
+			quickOpen.previewKinds = append(quickOpen.previewKinds, kinds)
+		}
+	}
+}
+
+func drawQuickOpen(ui *UIState) {
+	if len(ui.InputBoxes) == 0 {
+		closeQuickOpen()
+		return
+	}
+	box := ui.InputBoxes[0]
+	before := box.Text
+	box.HandleInput()
+	if box.Text != before {
+		ui.QuickOpenSelected = 0
+		ui.QuickOpenScroll = 0
+	}
+	updateQuickOpenResults(box.Text)
+	results := quickOpen.results
+
+	// layout: filter on top, results on the left, preview on the right
+	w := int32(min(1100, windowWidth-40))
+	h := int32(windowHeight - editorTopPadding - editorBottomPadding - 40)
+	x := int32(windowWidth)/2 - w/2
+	y := int32(editorTopPadding + 20)
+	listW := w * 45 / 100
+	listY := y + 48
+	visible := max(1, int(h-56)/quickOpenRowHeight)
+
+	if rl.IsKeyPressed(rl.KeyEscape) {
+		closeQuickOpen()
+		return
+	}
+	if (rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown)) && ui.QuickOpenSelected < len(results)-1 {
+		ui.QuickOpenSelected++
+	}
+	if (rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp)) && ui.QuickOpenSelected > 0 {
+		ui.QuickOpenSelected--
+	}
+	if rl.IsKeyPressed(rl.KeyPageDown) {
+		ui.QuickOpenSelected = min(ui.QuickOpenSelected+visible, len(results)-1)
+	}
+	if rl.IsKeyPressed(rl.KeyPageUp) {
+		ui.QuickOpenSelected = max(ui.QuickOpenSelected-visible, 0)
+	}
+	if rl.IsKeyPressed(rl.KeyEnter) && ui.QuickOpenSelected < len(results) {
+		box.AddToHistory()
+		closeQuickOpen()
+		openFileInEditor(filepath.FromSlash(results[ui.QuickOpenSelected].Path))
+		return
+	}
+
+	if ui.QuickOpenSelected < ui.QuickOpenScroll {
+		ui.QuickOpenScroll = ui.QuickOpenSelected
+	}
+	if ui.QuickOpenSelected >= ui.QuickOpenScroll+visible {
+		ui.QuickOpenScroll = ui.QuickOpenSelected - visible + 1
+	}
+	if wheel := rl.GetMouseWheelMove(); wheel != 0 {
+		ui.QuickOpenScroll -= int(wheel * 3)
+		ui.QuickOpenScroll = max(0, min(ui.QuickOpenScroll, len(results)-visible))
+	}
+
+	rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), ModernOverlay)
+	drawShadow(float32(x), float32(y), float32(w), float32(h), 4, 8)
+	rl.DrawRectangle(x, y, w, h, ModernMedium)
+	rl.DrawRectangleLines(x, y, w, h, ModernBorder)
+
+	box.Rect = rl.NewRectangle(float32(x+8), float32(y+8), float32(w-16), 32)
+	box.Draw()
+
+	_, _, building, _ := projectIndex.snapshot()
+	switch {
+	case building && len(results) == 0:
+		DrawText("Indexing files...", int(x)+16, int(listY)+4, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		return
+	case len(results) == 0:
+		DrawText("No matching files", int(x)+16, int(listY)+4, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		return
+	}
+
+	// results
+	maxChars := int(listW-24) / CHAR_IMAGE_WIDTH
+	mouseX, mouseY := rl.GetMouseX(), rl.GetMouseY()
+	for i := 0; i < visible; i++ {
+		idx := ui.QuickOpenScroll + i
+		if idx >= len(results) {
+			break
+		}
+		r := results[idx]
+		rowY := listY + int32(i*quickOpenRowHeight)
+		hover := mouseX >= x && mouseX < x+listW && mouseY >= rowY && mouseY < rowY+quickOpenRowHeight
+
+		if idx == ui.QuickOpenSelected {
+			rl.DrawRectangle(x+4, rowY, listW-8, quickOpenRowHeight, ModernLight)
+		} else if hover {
+			rl.DrawRectangle(x+4, rowY, listW-8, quickOpenRowHeight, ModernDarkButton)
+		}
+
+		// long paths keep their end, the file name matters most
+		text, positions := r.Path, r.Positions
+		if len(text) > maxChars {
+			cut := len(text) - maxChars + 3
+			text = "..." + text[cut:]
+			shifted := make([]int, 0, len(positions))
+			for _, p := range positions {
+				if p >= cut {
+					shifted = append(shifted, p-cut+3)
+				}
+			}
+			positions = shifted
+		}
+		drawMatchedText(text, positions, int(x)+12, int(rowY)+4, maxChars)
+
+		if hover && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+			if idx == ui.QuickOpenSelected {
+				box.AddToHistory()
+				closeQuickOpen()
+				openFileInEditor(filepath.FromSlash(r.Path))
+				return
+			}
+			ui.QuickOpenSelected = idx
+		}
+	}
+
+	// preview of the highlighted file
+	px := x + listW + 8
+	pw := w - listW - 16
+	ph := h - 56
+	rl.DrawRectangle(px, listY, pw, ph, ModernDarkBg)
+	maxLines := int(ph-8) / CHAR_IMAGE_HEIGHT
+	loadQuickOpenPreview(results[ui.QuickOpenSelected].Path, maxLines)
+	if quickOpen.previewNote != "" {
+		DrawText(quickOpen.previewNote, int(px)+8, int(listY)+8, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+		return
+	}
+	cols := int(pw-16) / CHAR_IMAGE_WIDTH
+	for i, line := range quickOpen.previewLines {
+		if i >= maxLines {
+			break
+		}
+		var kinds []TokenKind
+		if i < len(quickOpen.previewKinds) {
+			kinds = quickOpen.previewKinds[i]
+		}
+		for j := 0; j < len(line) && j < cols; j++ {
+			ch := line[j]
+			if ch < 32 || ch > 126 {
+				ch = '?'
+			}
+			DrawCharacter(ch, int(px)+8+j*CHAR_IMAGE_WIDTH, int(listY)+4+i*CHAR_IMAGE_HEIGHT, rl.DrawPixel, colorForKinds(kinds, j))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// current search term, set with Ctrl+F from the selection or the word under the cursor
+var searchQuery string
+
+type searchLinesCache struct {
+	query   string
+	version int
+	lines   []int
+}
+
+var searchCache searchLinesCache
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+// word around column x of line y, empty when the cursor is not on a word
+func wordAt(x, y int) string {
+	start, end, ok := wordBounds(x, y)
+	if !ok {
+		return ""
+	}
+	return string(rowContent(y)[start:end])
+}
+
+// columns [start, end) of the word at or just left of column x
+func wordBounds(x, y int) (int, int, bool) {
+	content := rowContent(y)
+	if x >= len(content) || !isWordChar(content[x]) {
+		if x == 0 || x > len(content) || !isWordChar(content[x-1]) {
+			return 0, 0, false
+		}
+		x--
+	}
+	start, end := x, x
+	for start > 0 && isWordChar(content[start-1]) {
+		start--
+	}
+	for end < len(content) && isWordChar(content[end]) {
+		end++
+	}
+	return start, end, true
+}
+
+// Ctrl+F: search for the selected text (single line only) or the word under the cursor
+func searchFromCursor() {
+	query := ""
+	if selection.Active {
+		query = getSelectedText()
+		if strings.Contains(query, "\n") {
+			query = ""
+		}
+	}
+	if query == "" {
+		query = wordAt(cursor.x, cursor.y)
+	}
+	setSearchQuery(query)
+}
+
+func setSearchQuery(query string) {
+	searchQuery = query
+	if query == "" {
+		editorStatus = "Search cleared"
+		return
+	}
+	editorStatus = "Search: " + query + " (F3 next)"
+}
+
+// lines containing the search term, cached until the buffer or query changes
+func searchMatchLines() []int {
+	if searchQuery == "" {
+		return nil
+	}
+	if searchCache.query == searchQuery && searchCache.version == bufferVersion {
+		return searchCache.lines
+	}
+
+	searchCache.query = searchQuery
+	searchCache.version = bufferVersion
+	searchCache.lines = searchCache.lines[:0]
+	query := []byte(searchQuery)
+	for y := 0; y < usedRows; y++ {
+		if bytes.Contains(rowContent(y), query) {
+			searchCache.lines = append(searchCache.lines, y)
+		}
+	}
+	return searchCache.lines
+}
+
+// F3: selects the next match after the cursor, wrapping around at the end of the file
+func findNext() {
+	if searchQuery == "" {
+		editorStatus = "Nothing to search, Ctrl+F on a word first"
+		return
+	}
+	query := []byte(searchQuery)
+
+	for i := 0; i <= usedRows; i++ {
+		y := (cursor.y + i) % usedRows
+		content := rowContent(y)
+		from := 0
+		if i == 0 {
+			from = cursor.x + 1
+		}
+		if from > len(content) {
+			continue
+		}
+		idx := bytes.Index(content[from:], query)
+		if idx == -1 {
+			continue
+		}
+
+		x := from + idx
+		selection.reset()
+		selection.Active = true
+		selection.StartX = x
+		selection.StartY = y
+		selection.EndX = x + len(query) - 1
+		selection.EndY = y
+		cursor.x = x
+		cursor.y = y
+		ensureCursorVisible(cursor)
+		return
+	}
+	editorStatus = "No match for " + searchQuery
+}
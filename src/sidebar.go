@@ -0,0 +1,288 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Project sidebar: the working directory as a tree docked on the left. Folders
+// are read when they are first expanded and re-read when their mod time changes,
+// the file of the active buffer is highlighted. Ctrl+B toggles it, the right
+// edge can be dragged to resize it.
+
+const sidebarRowHeight = 20
+const sidebarIndent = 12
+const sidebarMinWidth = 120
+
+type treeNode struct {
+	FileEntry
+	path     string
+	depth    int
+	expanded bool
+	loaded   bool
+	modTime  time.Time // of the folder when its children were read
+	children []*treeNode
+}
+
+var sidebar struct {
+	root      *treeNode
+	scroll    int
+	resizing  bool
+	lastCheck time.Time
+}
+
+// width taken from the editor, 0 when hidden
+func sidebarWidth() int {
+	if !settings.Sidebar {
+		return 0
+	}
+	width := settings.SidebarWidth
+	if width <= 0 {
+		width = 220
+	}
+	return max(sidebarMinWidth, min(width, windowWidth/2))
+}
+
+// x where the editor area starts, right of the sidebar
+func editorLeft() int {
+	return sidebarWidth()
+}
+
+func toggleSidebar() {
+	settings.Sidebar = !settings.Sidebar
+	sidebar.resizing = false
+	if settings.Sidebar {
+		sidebarRoot()
+	}
+	ensureCursorVisible(cursor)
+}
+
+func sidebarRoot() *treeNode {
+	if sidebar.root == nil {
+		dir, err := filepath.Abs(".")
+		if err != nil {
+			dir = "."
+		}
+		sidebar.root = &treeNode{FileEntry: FileEntry{Name: filepath.Base(dir), IsFolder: true}, path: dir, expanded: true}
+		sidebar.root.load()
+	}
+	return sidebar.root
+}
+
+// (re)reads the folder, expanded subfolders keep their state
+func (n *treeNode) load() {
+	old := map[string]*treeNode{}
+	for _, c := range n.children {
+		old[c.Name] = c
+	}
+	if info, err := os.Stat(n.path); err == nil {
+		n.modTime = info.ModTime()
+	}
+
+	n.children = n.children[:0]
+	for _, e := range listAllFiles(n.path) {
+		if strings.HasPrefix(e.Name, ".") {
+			continue
+		}
+		if c, ok := old[e.Name]; ok && c.IsFolder == e.IsFolder {
+			c.FileEntry = e
+			n.children = append(n.children, c)
+			continue
+		}
+		n.children = append(n.children, &treeNode{FileEntry: e, path: filepath.Join(n.path, e.Name), depth: n.depth + 1})
+	}
+	n.loaded = true
+}
+
+// re-reads expanded folders whose contents changed, checked once a second
+func refreshSidebar() {
+	if !settings.Sidebar || time.Since(sidebar.lastCheck) < time.Second {
+		return
+	}
+	sidebar.lastCheck = time.Now()
+
+	var check func(n *treeNode)
+	check = func(n *treeNode) {
+		if !n.IsFolder || !n.loaded {
+			return
+		}
+		if info, err := os.Stat(n.path); err == nil && !info.ModTime().Equal(n.modTime) {
+			n.load()
+		}
+		if !n.expanded {
+			return
+		}
+		for _, c := range n.children {
+			check(c)
+		}
+	}
+	check(sidebarRoot())
+}
+
+// rows on screen, the root itself is not shown
+func sidebarRows() []*treeNode {
+	var rows []*treeNode
+	var walk func(n *treeNode)
+	walk = func(n *treeNode) {
+		for _, c := range n.children {
+			rows = append(rows, c)
+			if c.IsFolder && c.expanded {
+				walk(c)
+			}
+		}
+	}
+	walk(sidebarRoot())
+	return rows
+}
+
+func (n *treeNode) toggle() {
+	n.expanded = !n.expanded
+	if n.expanded && !n.loaded {
+		n.load()
+	}
+}
+
+func sidebarVisibleRows() int {
+	return max(1, (windowHeight-editorTopPadding-editorBottomPadding-24)/sidebarRowHeight)
+}
+
+func mouseOverSidebar() bool {
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+	return settings.Sidebar && mouseX < sidebarWidth()+3 &&
+		mouseY >= editorTopPadding && mouseY < windowHeight-editorBottomPadding
+}
+
+// clicks, wheel and edge dragging, called before the editor mouse handling
+func handleSidebarInput() {
+	if !settings.Sidebar {
+		return
+	}
+	refreshSidebar()
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+	width := sidebarWidth()
+
+	// resize by dragging the right edge
+	onEdge := mouseX >= width-3 && mouseX <= width+3 &&
+		mouseY >= editorTopPadding && mouseY < windowHeight-editorBottomPadding
+	if onEdge || sidebar.resizing {
+		rl.SetMouseCursor(rl.MouseCursorResizeEW)
+	} else if mouseOverSidebar() {
+		rl.SetMouseCursor(rl.MouseCursorDefault)
+	}
+	if rl.IsMouseButtonPressed(rl.MouseLeftButton) && onEdge {
+		sidebar.resizing = true
+	}
+	if sidebar.resizing {
+		if rl.IsMouseButtonDown(rl.MouseLeftButton) {
+			settings.SidebarWidth = max(sidebarMinWidth, min(mouseX, windowWidth/2))
+		} else {
+			sidebar.resizing = false
+			rl.SetMouseCursor(rl.MouseCursorDefault)
+			if err := saveSetting("sidebarWidth", settings.SidebarWidth); err != nil {
+				notifyError("Could not save config.json: %v", err)
+			}
+			ensureCursorVisible(cursor)
+		}
+		return
+	}
+
+	if !mouseOverSidebar() {
+		return
+	}
+	rows := sidebarRows()
+	visible := sidebarVisibleRows()
+	if wheel := rl.GetMouseWheelMove(); wheel != 0 {
+		sidebar.scroll -= int(wheel * 3)
+	}
+	sidebar.scroll = max(0, min(sidebar.scroll, len(rows)-visible))
+
+	left, right := rl.IsMouseButtonPressed(rl.MouseLeftButton), rl.IsMouseButtonPressed(rl.MouseRightButton)
+	if left || right {
+		idx := sidebar.scroll + (mouseY-editorTopPadding-24)/sidebarRowHeight
+		if mouseY < editorTopPadding+24 || idx >= len(rows) {
+			return
+		}
+		n := rows[idx]
+		open := func() {
+			if n.IsFolder {
+				n.toggle()
+			} else {
+				openFileInEditor(n.path)
+			}
+		}
+		if right {
+			openContextMenu(fileContextItems(n.path, n.IsFolder, open))
+		} else {
+			open()
+		}
+	}
+}
+
+func drawSidebar() {
+	if !settings.Sidebar {
+		return
+	}
+	width := sidebarWidth()
+	top := editorTopPadding
+	height := windowHeight - editorTopPadding - editorBottomPadding
+
+	rl.DrawRectangle(0, int32(top), int32(width), int32(height), ModernDark)
+	rl.DrawRectangle(int32(width-1), int32(top), 1, int32(height), ModernBorder)
+	if sidebar.resizing {
+		rl.DrawRectangle(int32(width-2), int32(top), 3, int32(height), ModernAccent)
+	}
+
+	root := sidebarRoot()
+	maxChars := (width - 12) / CHAR_IMAGE_WIDTH
+	title := strings.ToUpper(root.Name)
+	if len(title) > maxChars {
+		title = title[:maxChars]
+	}
+	DrawText(title, 8, top+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+
+	active := ""
+	if currentFile != "Untitled" {
+		active, _ = filepath.Abs(currentFile)
+	}
+
+	rows := sidebarRows()
+	mouseX, mouseY := int(rl.GetMouseX()), int(rl.GetMouseY())
+	listY := top + 24
+	for i := 0; i < sidebarVisibleRows(); i++ {
+		idx := sidebar.scroll + i
+		if idx >= len(rows) {
+			break
+		}
+		n := rows[idx]
+		y := listY + i*sidebarRowHeight
+		hover := mouseX < width-3 && mouseY >= y && mouseY < y+sidebarRowHeight
+
+		switch {
+		case n.path == active:
+			rl.DrawRectangle(0, int32(y), int32(width-1), sidebarRowHeight, ModernLight)
+		case hover:
+			rl.DrawRectangle(0, int32(y), int32(width-1), sidebarRowHeight, ModernDarkButton)
+		}
+
+		x := 8 + (n.depth-1)*sidebarIndent
+		label, color := n.Name, "ui.text"
+		if n.IsFolder {
+			color = "ui.accent"
+			if n.expanded {
+				label = "v " + label
+			} else {
+				label = "> " + label
+			}
+		} else {
+			label = "  " + label
+		}
+		if room := (width - x - 4) / CHAR_IMAGE_WIDTH; len(label) > room {
+			label = label[:max(0, room)]
+		}
+		DrawText(label, x, y+sidebarRowHeight/2-CHAR_IMAGE_HEIGHT/2, CHAR_IMAGE_WIDTH, rl.DrawPixel, color)
+	}
+}
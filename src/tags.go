@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Note metadata and tags. A note can start with a front matter header
+//
+//	---
+//	title: Weekly sync
+//	tags: [work, meetings]
+//	created: 2026-10-19
+//	updated: 2026-10-20
+//	---
+//
+// and tag itself anywhere in the text with #tag. Both are read when a note is
+// indexed (on save and when the index is synced), the Notes panel browses the
+// tags and typing # in a note completes the tags of its notebook.
+
+type noteMeta struct {
+	Title   string   `json:"title,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Created string   `json:"created,omitempty"`
+	Updated string   `json:"updated,omitempty"`
+}
+
+const maxTagCompletions = 8
+
+// letters, digits, _ - / and any non ASCII byte, tags are matched on bytes like the grid
+func isTagChar(ch byte) bool {
+	return isWordChar(ch) || ch == '-' || ch == '/' || ch >= 0x80
+}
+
+// a tag starts with a letter, "#1" or "#!" are no tags
+func isTagStart(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch >= 0x80
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(tag), "#\"'"))
+}
+
+// the #tags of a line. A # after a word character or & (url#anchor, &#39;) does
+// not start a tag
+func inlineTags(line string) []string {
+	var tags []string
+	for i := 0; i < len(line)-1; i++ {
+		if line[i] != '#' || !isTagStart(line[i+1]) {
+			continue
+		}
+		if i > 0 && (isTagChar(line[i-1]) || line[i-1] == '&' || line[i-1] == '#') {
+			continue
+		}
+		end := i + 1
+		for end < len(line) && isTagChar(line[end]) {
+			end++
+		}
+		tags = append(tags, normalizeTag(strings.TrimRight(line[i+1:end], "-/")))
+		i = end - 1
+	}
+	return tags
+}
+
+// reads the front matter and the inline tags of a note. A first line "---" starts
+// front matter only when a closing "---" follows, else it is a rule of the text
+func parseNoteMeta(text string) noteMeta {
+	var meta noteMeta
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var header []string
+	body := 0
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				header = lines[1:i]
+				body = i + 1
+				break
+			}
+		}
+	}
+	key := ""
+	for _, line := range header {
+		// "- tag" lines below "tags:"
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+			if key == "tags" {
+				meta.Tags = append(meta.Tags, normalizeTag(item))
+			}
+			continue
+		}
+		k, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(k))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "title":
+			meta.Title = strings.Trim(value, "\"'")
+		case "tags":
+			value = strings.Trim(value, "[]")
+			for _, t := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+				meta.Tags = append(meta.Tags, normalizeTag(t))
+			}
+		case "created":
+			meta.Created = value
+		case "updated":
+			meta.Updated = value
+		}
+	}
+	for _, line := range lines[body:] {
+		meta.Tags = append(meta.Tags, inlineTags(line)...)
+	}
+
+	// without duplicates, sorted
+	tags := meta.Tags[:0]
+	seen := map[string]bool{}
+	for _, t := range meta.Tags {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	meta.Tags = tags
+	return meta
+}
+
+// ------------------------------------------------------------------------------------
+
+var notesTagCounts map[string]int
+
+// Notes panel: every tag of the notebook with the number of its notes
+func showNotesTags() {
+	ix := notesIndexFor(notesRoot())
+	ix.sync()
+	notesTagCounts = ix.tagCounts()
+	tags := make([]string, 0, len(notesTagCounts))
+	for t := range notesTagCounts {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+
+	ui.NotesTagView = true
+	ui.NotesTag = ""
+	ui.NotesPath = ""
+	ui.Notes = tags
+	ui.NotesScroll = 0
+}
+
+// Notes panel: the notes with the tag, paths relative to the notebook root
+func showNotesTagged(tag string) {
+	ix := notesIndexFor(notesRoot())
+	ui.NotesTagView = true
+	ui.NotesTag = tag
+	ui.NotesPath = ""
+	ui.Notes = ix.notesTagged(tag)
+	ui.NotesScroll = 0
+}
+
+// Notes panel: the folders and notes of a folder below the notebook root
+func showNotesFolder(dir string) {
+	ui.NotesTagView = false
+	ui.NotesTag = ""
+	ui.NotesPath = dir
+	ui.Notes = listNoteFiles(dir)
+	ui.NotesScroll = 0
+}
+
+func browseNoteTags() {
+	ui.ShowNotesPanel = true
+	showNotesTags()
+}
+
+// ------------------------------------------------------------------------------------
+
+// tag completion, a list below the cursor while a #tag is typed in a note
+var tagCompletion struct {
+	active   bool
+	pane     *Pane
+	line     int // position of the #
+	start    int
+	prefix   string
+	items    []string
+	counts   map[string]int
+	selected int
+}
+
+func closeTagCompletion() {
+	tagCompletion.active = false
+	tagCompletion.items = nil
+}
+
+// the tag being typed left of the cursor, start is the column of the #
+func tagBeforeCursor() (int, string, bool) {
+	row := rowContent(cursor.y)
+	x := min(cursor.x, len(row))
+	start := x
+	for start > 0 && isTagChar(row[start-1]) {
+		start--
+	}
+	if start == 0 || row[start-1] != '#' {
+		return 0, "", false
+	}
+	hash := start - 1
+	if hash > 0 && (isTagChar(row[hash-1]) || row[hash-1] == '&' || row[hash-1] == '#') {
+		return 0, "", false
+	}
+	if start < x && !isTagStart(row[start]) {
+		return 0, "", false
+	}
+	return hash, strings.ToLower(string(row[start:x])), true
+}
+
+// called after typing, opens or filters the list
+func updateTagCompletion() {
+	ix := notebookIndexFor(currentFile)
+	if ix == nil {
+		closeTagCompletion()
+		return
+	}
+	hash, prefix, ok := tagBeforeCursor()
+	if !ok {
+		closeTagCompletion()
+		return
+	}
+	if !ix.synced {
+		ix.sync()
+	}
+	counts := ix.tagCounts()
+	var items []string
+	for t := range counts {
+		if strings.HasPrefix(t, prefix) && t != prefix {
+			items = append(items, t)
+		}
+	}
+	if len(items) == 0 {
+		closeTagCompletion()
+		return
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if counts[items[i]] != counts[items[j]] {
+			return counts[items[i]] > counts[items[j]]
+		}
+		return items[i] < items[j]
+	})
+	if len(items) > maxTagCompletions {
+		items = items[:maxTagCompletions]
+	}
+	tagCompletion.active = true
+	tagCompletion.pane = focusedPane
+	tagCompletion.line = cursor.y
+	tagCompletion.start = hash
+	tagCompletion.prefix = prefix
+	tagCompletion.items = items
+	tagCompletion.counts = counts
+	tagCompletion.selected = 0
+}
+
+// keys of the open list, true when the key was taken. The list closes when the
+// cursor leaves the tag
+func handleTagCompletionKeys() bool {
+	tc := &tagCompletion
+	if !tc.active {
+		return false
+	}
+	if hash, _, ok := tagBeforeCursor(); !ok || hash != tc.start || cursor.y != tc.line || focusedPane != tc.pane {
+		closeTagCompletion()
+		return false
+	}
+	switch {
+	case rl.IsKeyPressed(rl.KeyEscape):
+		closeTagCompletion()
+	case rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown):
+		tc.selected = (tc.selected + 1) % len(tc.items)
+	case rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp):
+		tc.selected = (tc.selected + len(tc.items) - 1) % len(tc.items)
+	case rl.IsKeyPressed(rl.KeyEnter) || rl.IsKeyPressed(rl.KeyTab):
+		acceptTagCompletion(tc.items[tc.selected])
+	default:
+		return false
+	}
+	return true
+}
+
+// replaces the typed part of the tag, one undo step
+func acceptTagCompletion(tag string) {
+	undoStack = append(undoStack, takeSnapshot())
+	redoStack = nil
+	for cursor.x > tagCompletion.start+1 {
+		cursor.backspaceSingle()
+	}
+	insertStringAtCursor(tag)
+	closeTagCompletion()
+	ensureCursorVisible(cursor)
+}
+
+func drawTagCompletion() {
+	tc := &tagCompletion
+	if !tc.active || modalOpen() {
+		return
+	}
+	var sx, sy int
+	var ok bool
+	withPane(tc.pane, func() { sx, sy, ok = gridToScreen(tc.start, tc.line) })
+	if !ok {
+		return
+	}
+
+	labels := make([]string, len(tc.items))
+	width := 0
+	for i, t := range tc.items {
+		labels[i] = fmt.Sprintf("#%-*s %3d", maxTagLen(tc.items), t, tc.counts[t])
+		width = max(width, len(labels[i]))
+	}
+	rowH := CHAR_IMAGE_HEIGHT + 4
+	w := width*CHAR_IMAGE_WIDTH + 16
+	h := len(labels)*rowH + 8
+	x := min(sx, windowWidth-w-4)
+	y := sy + CHAR_IMAGE_HEIGHT + editorYPadding
+	if y+h > windowHeight-editorBottomPadding {
+		y = sy - h
+	}
+
+	drawShadow(float32(x), float32(y), float32(w), float32(h), 3, 4)
+	rl.DrawRectangle(int32(x), int32(y), int32(w), int32(h), ModernMedium)
+	rl.DrawRectangleLines(int32(x), int32(y), int32(w), int32(h), ModernBorder)
+	for i, label := range labels {
+		ry := y + 4 + i*rowH
+		if i == tc.selected {
+			rl.DrawRectangle(int32(x+2), int32(ry), int32(w-4), int32(rowH), ModernLight)
+		}
+		positions := make([]int, len(tc.prefix)+1)
+		for j := range positions {
+			positions[j] = j
+		}
+		drawMatchedText(label, positions, x+8, ry+2, width)
+	}
+}
+
+func maxTagLen(tags []string) int {
+	n := 0
+	for _, t := range tags {
+		n = max(n, len(t))
+	}
+	return n
+}
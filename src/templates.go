@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Note templates, picked in the Create Note dialog. The bundled daily, meeting and
+// project templates can be replaced and more added as files in
+// <config>/templates (meeting.md, standup.txt, ...), the file name is the template
+// name. {{date}} (dd-mm-yyyy, like the daily notes and their [[links]]), {{time}},
+// {{weekday}} and {{title}} are filled in when the note is created.
+
+var bundledNoteTemplates = map[string]string{
+	"daily": `---
+title: {{weekday}} {{date}}
+tags: [daily]
+created: {{date}} {{time}}
+---
+
+## Tasks
+
+## Notes
+`,
+	"meeting": `---
+title: {{title}}
+tags: [meeting]
+created: {{date}} {{time}}
+---
+
+# {{title}}
+
+{{weekday}} {{date}} {{time}}
+Attendees:
+
+## Agenda
+
+## Notes
+
+## Action items
+`,
+	"project": `---
+title: {{title}}
+tags: [project]
+created: {{date}}
+---
+
+# {{title}}
+
+## Goal
+
+## Tasks
+
+## Log
+
+- {{date}}: started
+`,
+}
+
+// template files of <config>/templates by name
+func userNoteTemplates() map[string]string {
+	files := map[string]string{}
+	entries, err := os.ReadDir(configPath("templates"))
+	if err != nil {
+		return files
+	}
+	for _, e := range entries {
+		if e.IsDir() || !isNoteFile(e.Name()) {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		files[name] = filepath.Join(configPath("templates"), e.Name())
+	}
+	return files
+}
+
+// bundled and user templates, sorted
+func noteTemplateNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for name := range bundledNoteTemplates {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range userNoteTemplates() {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// text of a template, a user file wins over the bundled one of the same name
+func noteTemplateText(name string) (string, error) {
+	if path, ok := userNoteTemplates()[name]; ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading template %s: %w", name, err)
+		}
+		return string(data), nil
+	}
+	if text, ok := bundledNoteTemplates[name]; ok {
+		return text, nil
+	}
+	return "", fmt.Errorf("there is no template %q", name)
+}
+
+// fills in the placeholders, an empty title becomes the name of the note file
+func expandNoteTemplate(text string, t time.Time, title, path string) string {
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\t", "    ")
+	return strings.NewReplacer(
+		"{{date}}", t.Format("02-01-2006"),
+		"{{time}}", t.Format("15:04"),
+		"{{weekday}}", t.Weekday().String(),
+		"{{title}}", title,
+	).Replace(text)
+}
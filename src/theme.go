@@ -0,0 +1,306 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// bundled themes, more can be added to <config>/themes
+//
+//go:embed themes/*.json
+var bundledThemes embed.FS
+
+// A theme file maps color keys to "#rrggbb" or "#rrggbbaa":
+//
+//	{
+//	  "name": "Dark",
+//	  "colors": {"editor.background": "#1e203c", "ui.accent": "#6c75ff"},
+//	  "syntax": {"keyword": "#c678dd"}
+//	}
+//
+// keys that are missing keep the built in default.
+type themeFile struct {
+	Name   string            `json:"name"`
+	Colors map[string]string `json:"colors"`
+	Syntax map[string]string `json:"syntax"`
+}
+
+type Theme struct {
+	Name    string
+	Path    string // empty for bundled themes
+	Colors  map[string]rl.Color
+	Syntax  map[string]rl.Color
+	modTime time.Time
+}
+
+// every color a theme can set
+var themeColors = map[string]*rl.Color{
+	"editor.background":         &ModernDarkBg,
+	"editor.text":               &EditorTextColor,
+	"editor.cursor":             &EditorCursorColor,
+	"editor.selection":          &EditorSelectionColor,
+	"editor.currentLine":        &EditorCurrentLineColor,
+	"editor.gutter":             &EditorGutterColor,
+	"editor.lineNumber":         &LineNumberColor,
+	"editor.lineNumberActive":   &LineNumberActiveColor,
+	"editor.whitespace":         &WhitespaceColor,
+	"editor.trailingWhitespace": &TrailingWhitespaceColor,
+	"editor.controlChar":        &ControlCharColor,
+	"editor.controlBox":         &ControlBoxColor,
+	"scrollbar.track":           &ScrollTrackColor,
+	"scrollbar.thumb":           &ScrollThumbColor,
+	"minimap.background":        &MinimapBackgroundColor,
+	"minimap.viewport":          &MinimapViewportColor,
+	"minimap.cursor":            &MinimapCursorColor,
+	"minimap.match":             &MinimapMatchColor,
+	"minimap.modified":          &MinimapModifiedColor,
+	"ui.dark":                   &ModernDark,
+	"ui.button":                 &ModernDarkButton,
+	"ui.medium":                 &ModernMedium,
+	"ui.light":                  &ModernLight,
+	"ui.accent":                 &ModernAccent,
+	"ui.success":                &ModernSuccess,
+	"ui.danger":                 &ModernDanger,
+	"ui.warning":                &ModernWarning,
+	"ui.text":                   &ModernText,
+	"ui.textDim":                &ModernTextDim,
+	"ui.shadow":                 &ModernShadow,
+	"ui.border":                 &ModernBorder,
+	"ui.overlay":                &ModernOverlay,
+}
+
+var themes []*Theme
+var activeTheme *Theme
+
+// built in values, restored before a theme is applied so switching themes never
+// leaves colors of the previous one behind
+var defaultThemeColors map[string]rl.Color
+var defaultSyntaxColors map[string]rl.Color
+
+var lastThemeCheck time.Time
+
+func parseHexColor(s string) (rl.Color, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return rl.Color{}, fmt.Errorf("bad color %q, expected #rrggbb or #rrggbbaa", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return rl.Color{}, fmt.Errorf("bad color %q", s)
+	}
+	return rl.NewColor(uint8(v>>24), uint8(v>>16), uint8(v>>8), uint8(v)), nil
+}
+
+func parseTheme(data []byte) (*Theme, error) {
+	var tf themeFile
+	if err := json.Unmarshal(data, &tf); err != nil {
+		return nil, err
+	}
+	if tf.Name == "" {
+		return nil, fmt.Errorf("theme without a name")
+	}
+
+	t := &Theme{Name: tf.Name, Colors: map[string]rl.Color{}, Syntax: map[string]rl.Color{}}
+	for key, value := range tf.Colors {
+		if _, ok := themeColors[key]; !ok {
+			return nil, fmt.Errorf("%s: unknown color key %q", tf.Name, key)
+		}
+		c, err := parseHexColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", tf.Name, key, err)
+		}
+		t.Colors[key] = c
+	}
+	for key, value := range tf.Syntax {
+		if _, ok := tokenKindFromName(key); !ok || key == "default" {
+			return nil, fmt.Errorf("%s: unknown syntax key %q", tf.Name, key)
+		}
+		c, err := parseHexColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: syntax %s: %v", tf.Name, key, err)
+		}
+		t.Syntax[key] = c
+	}
+	return t, nil
+}
+
+// loads bundled and user themes and applies the one from the config (Dark by default)
+func loadThemes() {
+	if defaultThemeColors == nil {
+		defaultThemeColors = map[string]rl.Color{}
+		for key, c := range themeColors {
+			defaultThemeColors[key] = *c
+		}
+		defaultSyntaxColors = map[string]rl.Color{}
+		for key, c := range SyntaxColors {
+			defaultSyntaxColors[key] = c
+		}
+	}
+
+	themes = nil
+	bundled, _ := bundledThemes.ReadDir("themes")
+	for _, f := range bundled {
+		data, err := bundledThemes.ReadFile("themes/" + f.Name())
+		if err != nil {
+			continue
+		}
+		addTheme(data, "")
+	}
+
+	userDir := configPath("themes")
+	userFiles, _ := os.ReadDir(userDir)
+	for _, f := range userFiles {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(userDir, f.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			notifyError("Could not read theme file: %v", err)
+			continue
+		}
+		addTheme(data, path)
+	}
+
+	name := settings.Theme
+	if name == "" {
+		name = "Dark"
+	}
+	if t := findTheme(name); t != nil {
+		applyTheme(t)
+	} else if len(themes) > 0 {
+		notifyWarning("Theme %s not found, using %s", name, themes[0].Name)
+		applyTheme(themes[0])
+	} else {
+		applyThemeColors()
+	}
+}
+
+func addTheme(data []byte, path string) {
+	t, err := parseTheme(data)
+	if err != nil {
+		notifyError("Theme %s not loaded: %v", path, err)
+		return
+	}
+	t.Path = path
+	if path != "" {
+		if info, err := os.Stat(path); err == nil {
+			t.modTime = info.ModTime()
+		}
+	}
+	for i, old := range themes {
+		if strings.EqualFold(old.Name, t.Name) {
+			themes[i] = t
+			return
+		}
+	}
+	themes = append(themes, t)
+}
+
+func findTheme(name string) *Theme {
+	for _, t := range themes {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+func themeNames() []string {
+	var names []string
+	for _, t := range themes {
+		names = append(names, t.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func applyTheme(t *Theme) {
+	for key, c := range defaultThemeColors {
+		*themeColors[key] = c
+	}
+	for key, c := range defaultSyntaxColors {
+		SyntaxColors[key] = c
+	}
+
+	for key, c := range t.Colors {
+		*themeColors[key] = c
+	}
+	for key, c := range t.Syntax {
+		SyntaxColors[key] = c
+	}
+
+	activeTheme = t
+	settings.Theme = t.Name
+	applyThemeColors()
+}
+
+// makes the theme colors usable by name in DrawCharacter ("editor.text", "syntax.keyword", ...)
+func applyThemeColors() {
+	for key, c := range themeColors {
+		colorMap[key] = [3]byte{c.R, c.G, c.B}
+	}
+	for name, c := range SyntaxColors {
+		colorMap["syntax."+name] = [3]byte{c.R, c.G, c.B}
+	}
+}
+
+// picked from View > Theme
+func selectTheme(name string) {
+	t := findTheme(name)
+	if t == nil {
+		editorStatus = "Theme not found: " + name
+		return
+	}
+	applyTheme(t)
+	if err := saveSetting("theme", settings.Theme); err != nil {
+		notifyError("Could not save config.json: %v", err)
+	}
+	editorStatus = "Theme: " + t.Name
+}
+
+// reloads the active theme when its file changed on disk, checked twice a second
+func checkThemeReload() {
+	if activeTheme == nil || activeTheme.Path == "" || time.Since(lastThemeCheck) < 500*time.Millisecond {
+		return
+	}
+	lastThemeCheck = time.Now()
+
+	info, err := os.Stat(activeTheme.Path)
+	if err != nil || !info.ModTime().After(activeTheme.modTime) {
+		return
+	}
+
+	data, err := os.ReadFile(activeTheme.Path)
+	if err != nil {
+		return
+	}
+	t, err := parseTheme(data)
+	if err != nil {
+		// keep the old colors while the file is being edited
+		activeTheme.modTime = info.ModTime()
+		editorStatus = "Theme error: " + err.Error()
+		return
+	}
+	t.Path = activeTheme.Path
+	t.modTime = info.ModTime()
+	for i, old := range themes {
+		if old == activeTheme {
+			themes[i] = t
+		}
+	}
+	applyTheme(t)
+	editorStatus = "Theme reloaded: " + t.Name
+}
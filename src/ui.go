@@ -2,40 +2,49 @@ package main
 
 import (
 	"fmt"
-	"os"
+	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 type UIState struct {
-	ActiveMenu     string
-	ModalOpen      string
+	ActiveMenu     string  // title of the open menu, "" when closed
+	MenuPath       []int   // highlighted item in the open menu and each open submenu
+	Modals         []Modal // open dialogs, the last one is on top
 	InputBoxes     []*InputBox
 	CurrentView    string
 	ShowNotesPanel bool
 	Notes          []string
 	NotesScroll    int
-	NotesPath      string
-	IsFolderView   bool
+	NotesPath      string // folder below the notes root, "" for the root
+	NotesTagView   bool   // tags instead of folders
+	NotesTag       string // tag whose notes are listed
 
 	ShowFilePicker   bool
 	FileEntries      []FileEntry
 	CurrentPath      string
-	SelectedFile     string
-	SelectedIsFolder bool
+	PickerFilter     *InputBox
+	PickerSelected   int
+	PickerScroll     int
+	PickerShowHidden bool
+	PickerSort       string // "name", "size" or "mtime"
+	PickerSortDesc   bool
+	PickerAction     string // file operation waiting for a name / confirmation
+	PickerTarget     string // entry the action applies to
+	PickerPrompt     *InputBox
+	PickerError      string
+
+	PaletteSelected int
+	PaletteScroll   int
+
+	QuickOpenSelected int
+	QuickOpenScroll   int
 }
 
-type InputBox struct {
-	Rect     rl.Rectangle
-	Text     string
-	Focused  bool
-	MaxChars int
-}
-
-// new "modern" colors - basically the theme
+// new "modern" colors - basically the theme.
+// these are the defaults, the active theme file overrides them (see theme.go)
 var (
 	ModernDarkBg     = rl.NewColor(30, 32, 60, 255)    // Dark background
 	ModernDarkButton = rl.NewColor(30, 32, 48, 150)    // Dark button
@@ -45,117 +54,76 @@ var (
 	ModernAccent     = rl.NewColor(108, 117, 255, 255) // Primary accent
 	ModernSuccess    = rl.NewColor(72, 187, 120, 255)  // Success green
 	ModernDanger     = rl.NewColor(245, 101, 101, 255) // Danger red
+	ModernWarning    = rl.NewColor(236, 201, 75, 255)  // Warning yellow
 	ModernText       = rl.NewColor(226, 232, 240, 255) // Light text
 	ModernTextDim    = rl.NewColor(160, 174, 192, 255) // Dimmed text
 	ModernShadow     = rl.NewColor(0, 0, 0, 50)        // Subtle shadow
+	ModernBorder     = rl.NewColor(80, 80, 80, 255)    // Panel borders
+	ModernOverlay    = rl.NewColor(0, 0, 0, 128)       // Behind modals
+)
+
+// editor colors
+var (
+	EditorTextColor         = rl.NewColor(255, 255, 255, 255)
+	EditorCursorColor       = rl.NewColor(255, 0, 0, 255)
+	EditorSelectionColor    = rl.NewColor(73, 82, 122, 255)
+	EditorCurrentLineColor  = rl.NewColor(80, 82, 122, 100)
+	EditorGutterColor       = rl.NewColor(30, 32, 48, 255)
+	LineNumberColor         = rl.NewColor(128, 132, 156, 255)
+	LineNumberActiveColor   = rl.NewColor(255, 255, 255, 255)
+	WhitespaceColor         = rl.NewColor(160, 174, 192, 90)
+	TrailingWhitespaceColor = rl.NewColor(245, 101, 101, 70)
+	ControlCharColor        = rl.NewColor(255, 165, 0, 255)
+	ControlBoxColor         = rl.NewColor(245, 101, 101, 160)
+	ScrollTrackColor        = rl.NewColor(80, 80, 80, 255)
+	ScrollThumbColor        = rl.NewColor(130, 130, 130, 255)
+	MinimapBackgroundColor  = rl.NewColor(26, 28, 52, 255)
+	MinimapViewportColor    = rl.NewColor(108, 117, 255, 50)
+	MinimapCursorColor      = rl.NewColor(255, 0, 0, 200)
+	MinimapMatchColor       = rl.NewColor(229, 192, 123, 255)
+	MinimapModifiedColor    = rl.NewColor(72, 187, 120, 255)
 )
 
+// syntax highlighting colors, keyed by token kind name (see tokenKindNames)
+var SyntaxColors = map[string]rl.Color{
+	"keyword":  rl.NewColor(198, 120, 221, 255),
+	"type":     rl.NewColor(86, 182, 194, 255),
+	"builtin":  rl.NewColor(97, 175, 239, 255),
+	"constant": rl.NewColor(209, 154, 102, 255),
+	"string":   rl.NewColor(152, 195, 121, 255),
+	"number":   rl.NewColor(209, 154, 102, 255),
+	"comment":  rl.NewColor(127, 132, 156, 255),
+	"operator": rl.NewColor(160, 174, 192, 255),
+	"function": rl.NewColor(97, 175, 239, 255),
+	"variable": rl.NewColor(224, 108, 117, 255),
+	"heading":  rl.NewColor(108, 117, 255, 255),
+	"emphasis": rl.NewColor(229, 192, 123, 255),
+	"link":     rl.NewColor(86, 182, 194, 255),
+	"code":     rl.NewColor(152, 195, 121, 255),
+}
+
 func drawShadow(x, y, width, height, offset, blur float32) {
 	// just draw multiple rectangles with lowered alpha to create blur effect
 	for i := 0; i < int(blur); i++ {
 		alpha := uint8(float32(ModernShadow.A) * (1.0 - float32(i)/blur))
-		shadowColor := rl.NewColor(0, 0, 0, alpha)
+		shadowColor := rl.NewColor(ModernShadow.R, ModernShadow.G, ModernShadow.B, alpha)
 		rl.DrawRectangle(int32(x+offset+float32(i)), int32(y+offset+float32(i)), int32(width), int32(height), shadowColor)
 	}
 }
 
-func (box *InputBox) Draw() {
-	// draw shadow
-	drawShadow(box.Rect.X, box.Rect.Y, box.Rect.Width, box.Rect.Height, 2, 3)
-
-	bg := ModernMedium
-	if box.Focused {
-		bg = ModernLight
-	}
-
-	// draw panel
-	rl.DrawRectangle(int32(box.Rect.X), int32(box.Rect.Y), int32(box.Rect.Width), int32(box.Rect.Height), bg)
-
-	// draw border
-	borderColor := ModernLight
-	if box.Focused {
-		borderColor = ModernAccent
-	}
-	rl.DrawRectangleLines(int32(box.Rect.X), int32(box.Rect.Y), int32(box.Rect.Width), int32(box.Rect.Height), borderColor)
-
-	// draw text with better positioning
-	DrawText(box.Text, int(box.Rect.X)+12, int(box.Rect.Y)+8, 10, rl.DrawPixel, "white")
-}
-
-func (box *InputBox) HandleInput() {
-	// autofocus
-	box.Focused = true
-
-	if box.Focused {
-		char := rl.GetCharPressed()
-		for char > 0 {
-			if char >= 32 && char <= 126 && len(box.Text) < box.MaxChars {
-				box.Text += string(rune(char))
-			}
-			char = rl.GetCharPressed()
-		}
-		if (rl.IsKeyPressed(rl.KeyBackspace) || rl.IsKeyDown(rl.KeyBackspace)) && len(box.Text) > 0 {
-			box.Text = box.Text[:len(box.Text)-1]
-			time.Sleep(150 * time.Millisecond)
-			return
-		}
-	}
+func notesPanelRect() (int32, int32, int32, int32) {
+	return int32(windowWidth / 2 / 3), int32(windowHeight / 2 / 3), int32(windowWidth / 2), int32(windowHeight / 2)
 }
 
-func DrawMenuBar(ui *UIState) {
-	menuHeight := editorTopPadding
-
-	// Modern gradient-like background
-	rl.DrawRectangle(0, 0, int32(windowWidth), int32(menuHeight), ModernDark)
-	rl.DrawRectangle(0, int32(menuHeight-2), int32(windowWidth), 2, ModernAccent)
-
-	if DrawModernButton("File", 10, 6, 60, int32(menuHeight-12), ModernText, ModernAccent, ModernLight, ModernDark, true) {
-		if ui.ActiveMenu == "File" {
-			ui.ActiveMenu = ""
-		} else {
-			ui.ActiveMenu = "File"
-		}
-	}
-	if DrawModernButton("Notes", 80, 6, 70, int32(menuHeight-12), ModernText, ModernAccent, ModernLight, ModernDark, true) {
-		if ui.ShowNotesPanel {
-			ui.ShowNotesPanel = false
-		} else {
-			ui.NotesPath = ""
-			ui.Notes = listNoteFiles("notes", true) // list only folders
-			ui.IsFolderView = true
-			ui.ShowNotesPanel = true
-			ui.NotesScroll = 0
-		}
-	}
-
-	if DrawModernButton("Create Note", 160, 6, 110, int32(menuHeight-12), ModernText, ModernSuccess, ModernLight, ModernDark, true) {
-		ui.ModalOpen = "CreateNote"
-		ui.InputBoxes = []*InputBox{
-			{
-				Rect:     rl.NewRectangle(150, 150, 300, 40),
-				Text:     "",
-				MaxChars: 64,
-			},
-		}
-	}
-	if DrawModernButton("Delete Note", 280, 6, 110, int32(menuHeight-12), ModernText, ModernDanger, ModernLight, ModernDark, true) {
-		if strings.Contains(currentFile, "notes/") {
-			deleteFile(currentFile)
-			clearTextGrid()
-			resetUndoRedoStacks()
-		} else {
-			editorStatus = "Not a Note"
-		}
-	}
-
-	if ui.ActiveMenu == "File" {
-		DrawDropdown("File", 10, int32(menuHeight), ui)
+func mouseOverNotesPanel() bool {
+	if !ui.ShowNotesPanel {
+		return false
 	}
+	return mouseInRect(notesPanelRect())
 }
 
 func DrawNotesPanel(ui *UIState) {
-	panelX, panelY := int32(windowWidth/2/3), int32(windowHeight/2/3)
-	panelW, panelH := int32(windowWidth/2), int32(windowHeight/2)
+	panelX, panelY, panelW, panelH := notesPanelRect()
 	entryHeight := int32(28)
 	maxVisible := int(panelH-80) / int(entryHeight)
 
@@ -189,24 +157,61 @@ func DrawNotesPanel(ui *UIState) {
 	rl.DrawRectangle(panelX, panelY, panelW, panelH, ModernMedium)
 
 	// draw border around the entire panel
-	rl.DrawRectangleLines(panelX, panelY, panelW, panelH, rl.DarkGray)
+	rl.DrawRectangleLines(panelX, panelY, panelW, panelH, ModernBorder)
 
 	// header section
 	rl.DrawRectangle(panelX, panelY, panelW, 50, ModernDark)
 
-	DrawText("Notes", int(panelX)+16, int(panelY)+16, 12, rl.DrawPixel, "white")
-
-	// back Button if not root ""
+	title := "Notes"
 	if ui.NotesPath != "" {
+		title += " / " + ui.NotesPath
+	}
+	if ui.NotesTag != "" {
+		title = "#" + ui.NotesTag
+	} else if ui.NotesTagView {
+		title = "Tags"
+	}
+	DrawText(title, int(panelX)+16, int(panelY)+16, 12, rl.DrawPixel, "ui.text")
+
+	// notebook switcher, lists the notebooks at the mouse
+	notebook := currentNotebook()
+	label := "Notebook: " + notebook.Name + " v"
+	labelW := int32(len(label)*CHAR_IMAGE_WIDTH + 16)
+	if DrawModernButton(label, panelX+panelW-16-labelW, panelY+13, labelW, 24, ModernText, ModernAccent, ModernLight, ModernMedium, true) {
+		openContextMenu(notebookItems())
+	}
+	rootChars := int(panelW-labelW-32-8*CHAR_IMAGE_WIDTH) / CHAR_IMAGE_WIDTH
+	DrawText(clampPathLeft(notebook.Root, rootChars), int(panelX)+16+7*CHAR_IMAGE_WIDTH, int(panelY)+16, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.textDim")
+
+	// back Button if not root "" or a tag
+	if ui.NotesPath != "" || ui.NotesTag != "" {
 		if DrawModernButton("Back", panelX+16, panelY+panelH-30, 70, 24, ModernText, ModernAccent, ModernLight, ModernDarkButton, true) {
-			ui.NotesPath = ""
-			ui.Notes = listNoteFiles("notes", true)
-			ui.IsFolderView = true
-			ui.NotesScroll = 0
+			if ui.NotesTagView {
+				showNotesTags()
+			} else {
+				showNotesFolder(strings.TrimSuffix(path.Dir(ui.NotesPath), "."))
+			}
 			return
 		}
 	}
 
+	// switches between the folders and the tag browser
+	viewLabel := "Tags"
+	if ui.NotesTagView {
+		viewLabel = "Folders"
+	}
+	if DrawModernButton(viewLabel, panelX+panelW-246, panelY+panelH-30, 70, 24, ModernText, ModernAccent, ModernLight, ModernDarkButton, true) {
+		if ui.NotesTagView {
+			showNotesFolder("")
+		} else {
+			showNotesTags()
+		}
+		return
+	}
+	if DrawModernButton("Search", panelX+panelW-166, panelY+panelH-30, 70, 24, ModernText, ModernAccent, ModernLight, ModernDarkButton, true) {
+		openNotesSearch()
+		return
+	}
 	if DrawModernButton("Close", panelX+panelW-86, panelY+panelH-30, 70, 24, ModernText, ModernDanger, ModernLight, ModernDarkButton, true) {
 		ui.ShowNotesPanel = false
 		return
@@ -219,7 +224,7 @@ func DrawNotesPanel(ui *UIState) {
 		scrollBarH := panelH - 110
 
 		// scroll track
-		rl.DrawRectangle(scrollBarX, scrollBarY, 6, scrollBarH, rl.Gray)
+		rl.DrawRectangle(scrollBarX, scrollBarY, 6, scrollBarH, ScrollTrackColor)
 
 		// scroll thumb
 		thumbHeight := int32(30)
@@ -227,7 +232,7 @@ func DrawNotesPanel(ui *UIState) {
 		scrollRatio := float32(ui.NotesScroll) / float32(maxScroll)
 		thumbY := scrollBarY + int32(scrollRatio*float32(scrollBarH-thumbHeight))
 
-		rl.DrawRectangle(scrollBarX, thumbY, 6, thumbHeight, rl.White)
+		rl.DrawRectangle(scrollBarX, thumbY, 6, thumbHeight, ScrollThumbColor)
 	}
 
 	// visible list
@@ -255,473 +260,68 @@ func DrawNotesPanel(ui *UIState) {
 		}
 		// fmt.Println("len ui.notes", len(ui.Notes))
 
+		label := entry
+		tagList := ui.NotesTagView && ui.NotesTag == ""
+		if tagList {
+			label = fmt.Sprintf("#%s (%d)", entry, notesTagCounts[entry])
+		}
+
 		// clamp the file name > long_file_name.txt -> long_file_n... for example
-		entryName := clampName(entry, panelW-40-(CHAR_IMAGE_WIDTH*4), CHAR_IMAGE_WIDTH)
+		entryName := clampName(label, panelW-40-(CHAR_IMAGE_WIDTH*4), CHAR_IMAGE_WIDTH)
 		if DrawModernButton(entryName, panelX+16, y, panelW-32, 24, ModernText, ModernAccent, ModernLight, ModernMedium, false) {
-			if ui.IsFolderView {
-				// folder clicked
-				ui.NotesPath = entry
-				ui.Notes = listNoteFiles("notes/"+entry, false)
-				ui.IsFolderView = false
-				ui.NotesScroll = 0
-			} else {
-				// file clicked
-				fullPath := "/home/void/notes/" + ui.NotesPath + entry
-				clearTextGrid()
-				loadFileIntoTextGrid(fullPath)
-				cursor.reset()
-				currentFile = fullPath
-				editorStatus = "Loaded Note: " + entry
-				resetUndoRedoStacks()
-				ui.ShowNotesPanel = false
-			}
+			openNotesEntry(entry)
+		} else if !tagList && !mouseBlocked && rl.IsMouseButtonPressed(rl.MouseRightButton) && mouseInRect(panelX+16, y, panelW-32, 24) {
+			path := filepath.Join(notesRoot(), ui.NotesPath, entry)
+			openContextMenu(fileContextItems(path, strings.HasSuffix(entry, "/"), func() { openNotesEntry(entry) }))
 		}
 	}
 	ensureCursorVisible(cursor)
 }
 
-func DrawFilePickerPanel(ui *UIState) {
-	panelX, panelY := int32(windowWidth/2/3), int32(windowHeight/2/3)
-	panelW, panelH := int32(windowWidth/2)+50, int32(windowHeight/2)
-	entryHeight := int32(28)
-	maxVisible := int(panelH-80) / int(entryHeight)
-
-	// mouse wheel scrolling
-	mouseWheel := rl.GetMouseWheelMove()
-	if mouseWheel != 0 {
-		mouseX := rl.GetMouseX()
-		mouseY := rl.GetMouseY()
-		if mouseX >= panelX && mouseX <= panelX+panelW && mouseY >= panelY && mouseY <= panelY+panelH {
-			ui.NotesScroll -= int(mouseWheel)
-
-			maxScroll := len(ui.FileEntries) - maxVisible
-			if maxScroll < 0 {
-				maxScroll = 0
-			}
-			if ui.NotesScroll < 0 {
-				ui.NotesScroll = 0
-			}
-			if ui.NotesScroll > maxScroll {
-				ui.NotesScroll = maxScroll
-			}
-		}
-	}
-
-	// draw shadow
-	drawShadow(float32(panelX), float32(panelY), float32(panelW), float32(panelH), 4, 8)
-
-	// draw panel
-	rl.DrawRectangle(panelX, panelY, panelW, panelH, ModernMedium)
-	rl.DrawRectangleLines(panelX, panelY, panelW, panelH, rl.DarkGray)
-
-	// header section
-	rl.DrawRectangle(panelX, panelY, panelW, 50, ModernDark)
-	DrawText("File Picker", int(panelX)+16, int(panelY)+16, 12, rl.DrawPixel, "white")
-
-	// show current path
-	if ui.CurrentPath == "" {
-		ui.CurrentPath, _ = filepath.Abs(".") // Start here TODO: rework
-	}
-
-	DrawText("Path: "+ui.CurrentPath, int(panelX)+16, int(panelY)+35, 8, rl.DrawPixel, "white")
-
-	// back button (if not at root)
-	if ui.CurrentPath != "/" {
-		if DrawModernButton("Back", panelX+16, panelY+panelH-30, 70, 24, ModernText, ModernAccent, ModernLight, ModernDark, true) {
-			// Go up one directory
-			if strings.Contains(ui.CurrentPath, "/") {
-				ui.CurrentPath = filepath.Dir(ui.CurrentPath)
-				if ui.CurrentPath == "." {
-					ui.CurrentPath = ""
-				}
-			} else {
-				ui.CurrentPath = ""
-			}
-			ui.FileEntries = listAllFiles(ui.CurrentPath)
-			ui.NotesScroll = 0
-			return
-		}
-	}
-
-	// close button
-	if DrawModernButton("Close", panelX+panelW-86, panelY+panelH-30, 70, 24, ModernText, ModernDanger, ModernLight, ModernDark, true) {
-		ui.ShowFilePicker = false
+// enters a folder or a tag of the notes list or opens a note
+func openNotesEntry(entry string) {
+	if ui.NotesTagView && ui.NotesTag == "" {
+		showNotesTagged(entry)
 		return
 	}
-
-	// select button (only show if we have a selected file)
-	if ui.SelectedFile != "" && !ui.SelectedIsFolder {
-		if DrawModernButton("Select", panelX+panelW-166, panelY+panelH-30, 70, 24, ModernText, ModernSuccess, ModernLight, ModernDark, true) {
-			// handle file selection here
-			fullPath := filepath.Join(ui.CurrentPath, ui.SelectedFile)
-			// clearTextGrid()
-			code, err := loadFileIntoTextGrid(fullPath)
-			if code == -1 || err != nil {
-				currentFile = "Untitled"
-				loadStringIntoTextGrid(err.Error())
-				ensureCursorVisible(cursor)
-				resetUndoRedoStacks()
-				ui.ShowFilePicker = false
-
-				return
-			}
-			// cursor.reset()
-			currentFile = fullPath
-			editorStatus = "Loaded: " + ui.SelectedFile
-			ui.ShowFilePicker = false
-			ensureCursorVisible(cursor)
-			resetUndoRedoStacks()
-			return
-		}
-	}
-
-	// scroll bar
-	if len(ui.FileEntries) > maxVisible {
-		scrollBarX := panelX + panelW - 12
-		scrollBarY := panelY + 60
-		scrollBarH := panelH - 110
-
-		rl.DrawRectangle(scrollBarX, scrollBarY, 6, scrollBarH, rl.Gray)
-
-		thumbHeight := int32(30)
-		maxScroll := len(ui.FileEntries) - maxVisible
-		scrollRatio := float32(ui.NotesScroll) / float32(maxScroll)
-		thumbY := scrollBarY + int32(scrollRatio*float32(scrollBarH-thumbHeight))
-		rl.DrawRectangle(scrollBarX, thumbY, 6, thumbHeight, rl.White)
+	if strings.HasSuffix(entry, "/") {
+		// folder clicked
+		showNotesFolder(path.Join(ui.NotesPath, entry))
+		return
 	}
-
-	// file/folder list
-	startIdx := ui.NotesScroll
-	endIdx := startIdx + maxVisible
-	if endIdx > len(ui.FileEntries) {
-		endIdx = len(ui.FileEntries)
+	// file clicked
+	fullPath := filepath.Join(notesRoot(), ui.NotesPath, entry)
+	code, err := loadFileIntoTextGrid(fullPath)
+	if err != nil {
+		notifyError("Could not open note %s: %v", entry, err)
 	}
-
-	for i := startIdx; i < endIdx; i++ {
-		if i >= len(ui.FileEntries) {
-			continue
-		}
-
-		entry := ui.FileEntries[i]
-		y := panelY + 60 + int32((i-startIdx)*int(entryHeight))
-
-		// different styling for folders vs files
-		var displayName string
-		var textColor rl.Color = ModernText
-
-		if entry.IsFolder {
-			displayName = "[dir] " + entry.Name
-			textColor = ModernAccent
+	if code != -1 {
+		if err != nil {
+			// partly read, keep the text but not the name
+			currentFile = "Untitled"
 		} else {
-			displayName = "[file] " + entry.Name
-		}
-
-		// highlight selected item
-		bgColor := ModernMedium
-		if ui.SelectedFile == entry.Name {
-			bgColor = ModernLight
-		}
-
-		// draw all elements (files/folders)
-		// entryName := clampName(displayName, panelW-50-(CHAR_IMAGE_WIDTH*4), CHAR_IMAGE_WIDTH)
-		entryName := clampName(displayName, panelW-40-(CHAR_IMAGE_WIDTH*4), CHAR_IMAGE_WIDTH)
-		// if DrawModernButton(entryName, panelX+16, y, panelW-32, 24, ModernText, ModernAccent, ModernLight, ModernMedium, false) {
-
-		if DrawModernButton(entryName, panelX+16, y, panelW-32, 24, textColor, ModernAccent, ModernLight, bgColor, false) {
-			ui.SelectedFile = entry.Name
-			ui.SelectedIsFolder = entry.IsFolder
-
-			if entry.IsFolder {
-				// Double-click or enter folder
-				ui.CurrentPath = filepath.Join(ui.CurrentPath, entry.Name)
-				ui.FileEntries = listAllFiles(ui.CurrentPath)
-				ui.NotesScroll = 0
-				ui.SelectedFile = ""
-				ui.SelectedIsFolder = false
-			}
-		}
-	}
-
-}
-
-func DrawDropdown(menu string, x, y int32, ui *UIState) {
-	options := []string{"Open...", "Open Pick", "Save", "Save As...", "New"}
-	dropdownW := int32(120)
-	dropdownH := int32(len(options) * 32)
-
-	// draw shadow
-	drawShadow(float32(x), float32(y), float32(dropdownW), float32(dropdownH), 2, 4)
-
-	// draw dropdown panel
-	rl.DrawRectangle(x, y, dropdownW, dropdownH, ModernMedium)
-
-	for i, opt := range options {
-		btnY := y + int32(i*32)
-		if DrawModernButton(opt, x+4, btnY+4, dropdownW-8, 24, ModernText, ModernAccent, ModernLight, ModernMedium, true) {
-			switch opt {
-			case "Open...":
-				ui.ModalOpen = "OpenFile"
-				ui.InputBoxes = []*InputBox{
-					{
-						Rect:     rl.NewRectangle(150, 150, 300, 40),
-						Text:     "",
-						MaxChars: 64,
-					},
-				}
-			case "Save":
-				fmt.Println("Save triggered (implement me!)")
-				if currentFile == "Untitled" {
-					ui.ModalOpen = "SaveAs"
-					ui.InputBoxes = []*InputBox{
-						{
-							Rect:     rl.NewRectangle(150, 150, 300, 40),
-							Text:     "",
-							MaxChars: 64,
-						},
-					}
-					continue
-				}
-				err := saveTextGridToFile(currentFile)
-				if err != nil {
-					currentFile = "Untitled"
-					loadStringIntoTextGrid(err.Error())
-					ui.ActiveMenu = ""
-					resetUndoRedoStacks()
-					return
-				}
-				editorStatus = "Saved File!"
-				resetUndoRedoStacks()
-			case "Save As...":
-				ui.ModalOpen = "SaveAs"
-				ui.InputBoxes = []*InputBox{
-					{
-						Rect:     rl.NewRectangle(150, 150, 300, 40),
-						Text:     "",
-						MaxChars: 64,
-					},
-				}
-			case "New":
-				clearTextGrid()
-				cursor.reset()
-				resetUndoRedoStacks()
-				// printGrid(cursor)
-			case "Open Pick":
-				if ui.ShowFilePicker {
-					ui.ShowFilePicker = false
-				} else {
-					ui.ShowFilePicker = true
-					ui.CurrentPath = ""
-					ui.FileEntries = listAllFiles(".")
-					ui.NotesScroll = 0
-				}
-			}
-			ui.ActiveMenu = ""
+			editorStatus = "Loaded Note: " + entry
+			ui.ShowNotesPanel = false
 		}
+		cursor.reset()
+		resetUndoRedoStacks()
 	}
 }
 
-func DrawModal(ui *UIState) {
-	if ui.ModalOpen == "OpenFile" {
-		modalX := int32(100)
-		modalY := int32(50)
-		modalW := int32(windowWidth - 200)
-		modalH := int32(windowHeight - 150)
-
-		// TODO: Rework this
-		// draw modal blur
-		rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), rl.NewColor(0, 0, 0, 128))
-
-		// draw shadow
-		drawShadow(float32(modalX), float32(modalY), float32(modalW), float32(modalH), 6, 12)
-
-		// draw modal panel
-		rl.DrawRectangle(modalX, modalY, modalW, modalH, ModernMedium)
-
-		// header
-		rl.DrawRectangle(modalX, modalY, modalW, 60, ModernDark)
-
-		DrawText("Open File", int(modalX)+20, int(modalY)+20, 14, rl.DrawPixel, "white")
-
-		for _, ib := range ui.InputBoxes {
-			ib.Draw()
-			ib.HandleInput()
-		}
-
-		if DrawModernButton("Cancel", modalX+modalW-180, modalY+modalH-50, 80, 32, ModernText, ModernDanger, ModernLight, ModernMedium, true) {
-			ui.ModalOpen = ""
-		}
-
-		if DrawModernButton("Open", modalX+modalW-90, modalY+modalH-50, 80, 32, ModernText, ModernSuccess, ModernLight, ModernMedium, true) {
-			if len(ui.InputBoxes) > 0 {
-				filename := ui.InputBoxes[0].Text
-				fmt.Printf("open: %s\n", filename)
-				res, err := loadFileIntoTextGrid(filename)
-				if err != nil {
-					currentFile = "Untitled"
-					// clearTextGrid()
-					// cursor.reset()
-					loadStringIntoTextGrid(err.Error())
-					resetUndoRedoStacks()
-					ui.ModalOpen = ""
-					return
-				}
-				resetUndoRedoStacks()
-				fmt.Println("Loaded ", res, " bytes into text grid")
-			}
-			ui.ModalOpen = ""
-		}
-	}
-	if ui.ModalOpen == "SaveAs" {
-		modalX := int32(100)
-		modalY := int32(50)
-		modalW := int32(windowWidth - 200)
-		modalH := int32(windowHeight - 150)
-
-		// draw modal blur
-		rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), rl.NewColor(0, 0, 0, 128))
-
-		// draw shadow
-		drawShadow(float32(modalX), float32(modalY), float32(modalW), float32(modalH), 6, 12)
-
-		// draw modal panel
-		rl.DrawRectangle(modalX, modalY, modalW, modalH, ModernMedium)
-
-		// header
-		rl.DrawRectangle(modalX, modalY, modalW, 60, ModernDark)
-
-		DrawText("Save As", int(modalX)+20, int(modalY)+20, 14, rl.DrawPixel, "white")
-
-		for _, ib := range ui.InputBoxes {
-			ib.Draw()
-			ib.HandleInput()
-		}
-
-		if DrawModernButton("Cancel", modalX+modalW-180, modalY+modalH-50, 80, 32, ModernText, ModernDanger, ModernLight, ModernMedium, true) {
-			ui.ModalOpen = ""
-		}
-
-		if DrawModernButton("Save", modalX+modalW-90, modalY+modalH-50, 80, 32, ModernText, ModernSuccess, ModernLight, ModernMedium, true) {
-			if len(ui.InputBoxes) > 0 {
-				filename := ui.InputBoxes[0].Text
-				err := saveTextGridToFile(filename)
-				if err != nil {
-					currentFile = "Untitled"
-					loadStringIntoTextGrid(err.Error())
-					ui.ModalOpen = ""
-					resetUndoRedoStacks()
-					return
-				} else {
-					fmt.Println("File saved as", filename)
-					currentFile = filename
-				}
-			}
-			ui.ModalOpen = ""
-		}
-	}
-	if ui.ModalOpen == "CreateNote" {
-		modalX := int32(100)
-		modalY := int32(50)
-		modalW := int32(windowWidth - 200)
-		modalH := int32(windowHeight - 150)
-
-		// draw modal blur
-		rl.DrawRectangle(0, 0, int32(windowWidth), int32(windowHeight), rl.NewColor(0, 0, 0, 128))
-
-		// draw shadow
-		drawShadow(float32(modalX), float32(modalY), float32(modalW), float32(modalH), 6, 12)
-
-		// draw modal panel
-		rl.DrawRectangle(modalX, modalY, modalW, modalH, ModernMedium)
-
-		// header
-		rl.DrawRectangle(modalX, modalY, modalW, 60, ModernDark)
-
-		DrawText("Create Note", int(modalX)+20, int(modalY)+20, 14, rl.DrawPixel, "white")
-
-		for _, ib := range ui.InputBoxes {
-			ib.Draw()
-			ib.HandleInput()
-		}
-
-		if DrawModernButton("Cancel", modalX+modalW-180, modalY+modalH-50, 80, 32, ModernText, ModernDanger, ModernLight, ModernMedium, true) {
-			ui.ModalOpen = ""
-		}
-
-		if DrawModernButton("Create", modalX+modalW-90, modalY+modalH-50, 80, 32, ModernText, ModernSuccess, ModernLight, ModernMedium, true) {
-			if len(ui.InputBoxes) > 0 {
-				filename := ui.InputBoxes[0].Text
-
-				now := time.Now()
-				folderName := now.Format("01-2006")
-				baseDir := "/home/void/"
-				// if err != nil {
-				// 	fmt.Printf("Error getting current working directory: %v\n", err)
-				// 	return
-				// }
-
-				folderPath := filepath.Join(baseDir, "notes/"+folderName)
-				fmt.Printf("Attempting to create folder: %s\n", folderPath)
-				_, err := os.Stat(folderPath)
-				if os.IsNotExist(err) {
-					err = os.MkdirAll(folderPath, 0755)
-					if err != nil {
-						fmt.Printf("Error creating folder %s: %v\n", folderPath, err)
-						return
-					}
-					fmt.Printf("Folder '%s' created successfully.\n", folderPath)
-				} else if err != nil {
-					fmt.Printf("Error checking folder existence %s: %v\n", folderPath, err)
-					return
-				} else {
-					fmt.Printf("Folder '%s' already exists.\n", folderPath)
-				}
-
-				fileName := now.Format("02-01-2006")
-				var filePath string
-				if filename == "" {
-					filePath = filepath.Join(folderPath, fileName)
-				} else {
-					filePath = filepath.Join(folderPath, fileName+" - "+filename)
-				}
-
-				fmt.Printf("Attempting to create file: %s\n", filePath)
-				filePath += ".txt"
-
-				_, err = os.Stat(filePath)
-				if os.IsNotExist(err) {
-					file, err := os.Create(filePath)
-					if err != nil {
-						fmt.Printf("Error creating file %s: %v\n", filePath, err)
-						return
-					}
-					defer file.Close()
-
-					fmt.Printf("File '%s' created successfully.\n", filePath)
-
-				} else if err != nil {
-					fmt.Printf("Error checking file existence %s: %v\n", filePath, err)
-					return
-				} else {
-					fmt.Printf("File '%s' already exists.\n", filePath)
-					ui.ModalOpen = ""
-					return
-				}
-
-				err = saveTextGridToFile(filePath)
-				if err != nil {
-					currentFile = "Untitled"
-					loadStringIntoTextGrid(err.Error())
-					ui.ModalOpen = ""
-					resetUndoRedoStacks()
-					return
-				} else {
-					fmt.Println("File saved as", filePath)
-					currentFile = filePath
-				}
-			}
-			ui.ModalOpen = ""
+// lists the notes folder shown again, after files changed
+func reloadNotesList() {
+	scroll := ui.NotesScroll
+	switch {
+	case ui.NotesTagView:
+		tag := ui.NotesTag
+		showNotesTags()
+		if tag != "" {
+			showNotesTagged(tag)
 		}
+	default:
+		ui.Notes = listNoteFiles(ui.NotesPath)
 	}
+	ui.NotesScroll = max(0, min(scroll, len(ui.Notes)-1))
 }
 
 func DrawStatusBar(cursor Cursor) {
@@ -732,14 +332,20 @@ func DrawStatusBar(cursor Cursor) {
 	rl.DrawRectangle(0, int32(windowHeight)-int32(barHeight), int32(windowWidth), 2, ModernAccent)
 
 	status := fmt.Sprintf("Ln %d, Col %d Buffer: %s | Status: %s", cursor.y+1, cursor.x+1, currentFile, editorStatus)
-	DrawText(status, 12, windowHeight-barHeight+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "white")
+	DrawText(status, 12, windowHeight-barHeight+5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
+}
+
+// set while drawing the panels below an open modal, their buttons ignore clicks
+var mouseBlocked bool
+
+func mouseInRect(x, y, w, h int32) bool {
+	mouseX, mouseY := rl.GetMouseX(), rl.GetMouseY()
+	return mouseX >= x && mouseX <= x+w && mouseY >= y && mouseY <= y+h
 }
 
 func DrawModernButton(label string, x, y, w, h int32, textColor rl.Color, pressColor, hoverColor, idleColor rl.Color, padding bool) bool {
-	mouseX := rl.GetMouseX()
-	mouseY := rl.GetMouseY()
-	mouseOver := mouseX >= x && mouseX <= x+w && mouseY >= y && mouseY <= y+h
-	pressed := rl.IsMouseButtonPressed(rl.MouseLeftButton)
+	mouseOver := mouseInRect(x, y, w, h)
+	pressed := rl.IsMouseButtonPressed(rl.MouseLeftButton) && !mouseBlocked
 
 	var bgColor rl.Color
 	if pressed && mouseOver {
@@ -756,7 +362,7 @@ func DrawModernButton(label string, x, y, w, h int32, textColor rl.Color, pressC
 	// simple text positioning - always left aligned with padding
 	// keeping padding bool as i cant be bothered to rewrite
 	// TODO: remove unused padding bool
-	DrawText(label, int(x)+8, int(y)+int(h)/2-5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "white")
+	DrawText(label, int(x)+8, int(y)+int(h)/2-5, CHAR_IMAGE_WIDTH, rl.DrawPixel, "ui.text")
 
 	return mouseOver && pressed
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func toggleShowWhitespace() {
+	settings.Whitespace.Show = !settings.Whitespace.Show
+	if settings.Whitespace.Show {
+		editorStatus = "Whitespace visible"
+	} else {
+		editorStatus = "Whitespace hidden"
+	}
+}
+
+// column where the trailing whitespace of line y starts, the line length if there is none
+func trailingWhitespaceStart(y int) int {
+	content := rowContent(y)
+	end := len(content)
+	for end > 0 && (content[end-1] == ' ' || content[end-1] == '\t') {
+		end--
+	}
+	if end == 0 {
+		// whitespace only lines are indentation, not trailing garbage
+		return len(content)
+	}
+	return end
+}
+
+// whitespace markers are drawn with primitives, the font has no glyphs for them
+func drawTrailingWhitespace(screenX, screenY int) {
+	rl.DrawRectangle(int32(screenX), int32(screenY), CHAR_IMAGE_WIDTH, CHAR_IMAGE_HEIGHT, TrailingWhitespaceColor)
+}
+
+// small centered dot
+func drawSpaceMarker(screenX, screenY int) {
+	rl.DrawRectangle(int32(screenX+CHAR_IMAGE_WIDTH/2-1), int32(screenY+CHAR_IMAGE_HEIGHT/2-1), 2, 2, WhitespaceColor)
+}
+
+// horizontal arrow over the whole cell
+func drawTabMarker(screenX, screenY int) {
+	midY := int32(screenY + CHAR_IMAGE_HEIGHT/2)
+	left := int32(screenX + 1)
+	right := int32(screenX + CHAR_IMAGE_WIDTH - 2)
+	rl.DrawLine(left, midY, right, midY, WhitespaceColor)
+	rl.DrawLine(right-3, midY-3, right, midY, WhitespaceColor)
+	rl.DrawLine(right-3, midY+3, right, midY, WhitespaceColor)
+}
+
+// pilcrow: filled bowl on the left plus two stems
+func drawLineEndMarker(screenX, screenY int) {
+	top := int32(screenY + 2)
+	height := int32(CHAR_IMAGE_HEIGHT - 4)
+	x := int32(screenX + 1)
+	rl.DrawRectangle(x, top, 4, 4, WhitespaceColor)
+	rl.DrawRectangle(x+4, top, 1, height, WhitespaceColor)
+	rl.DrawRectangle(x+6, top, 1, height, WhitespaceColor)
+	rl.DrawRectangle(x+4, top, 3, 1, WhitespaceColor)
+}
+
+// label used for a control or non ascii byte: ^M style for C0 controls and DEL,
+// hex for everything else (or everything when controlChars is "hex")
+func controlCharLabel(ch byte) string {
+	if settings.Whitespace.ControlChars != "hex" {
+		if ch < 32 {
+			return "^" + string(rune(ch+'@'))
+		}
+		if ch == 127 {
+			return "^?"
+		}
+	}
+	return fmt.Sprintf("%02x", ch)
+}
+
+// draws a control byte inside its single cell, the label glyphs are squeezed to half width
+func drawControlChar(ch byte, screenX, screenY int) {
+	if settings.Whitespace.ControlChars == "block" {
+		DrawCharacter(1, screenX, screenY, rl.DrawPixel, "editor.text")
+		return
+	}
+
+	label := controlCharLabel(ch)
+	if label[0] != '^' {
+		rl.DrawRectangleLines(int32(screenX), int32(screenY), CHAR_IMAGE_WIDTH, CHAR_IMAGE_HEIGHT, ControlBoxColor)
+	}
+	for i := 0; i < len(label) && i < 2; i++ {
+		drawHalfWidthCharacter(label[i], screenX+i*(CHAR_IMAGE_WIDTH/2), screenY, "editor.controlChar")
+	}
+}
+
+// like DrawCharacter but only every other pixel column, so two glyphs fit in one cell
+func drawHalfWidthCharacter(c byte, startX, startY int, color_ string) {
+	fontChar, ok := fontCharacters[c]
+	if !ok {
+		return
+	}
+	targetRGB, _ := getRGBForColor(color_)
+	for y := 0; y < fontChar.height; y++ {
+		for x := 0; x < fontChar.width; x += 2 {
+			col := fontChar.data[y*fontChar.width+x]
+			r := byte((col >> 16) & 0xFF)
+			g := byte((col >> 8) & 0xFF)
+			b := byte(col & 0xFF)
+			if calculateLuminance(r, g, b) > 10 {
+				r, g, b := adjustToTargetColor(r, g, b, targetRGB)
+				rl.DrawPixel(int32(startX+x/2), int32(startY+y), rl.NewColor(r, g, b, 255))
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+// A display row is one row of text on screen: a part [start, end) of a line in the
+// text grid. Without word wrap every visible line is one display row, cut by the
+// horizontal scroll. With word wrap a line is split into several rows at the last
+// space that fits, the text grid itself is never changed.
+type visualRow struct {
+	line  int
+	start int
+	end   int
+	first bool // first row of the line, gets the line number
+	last  bool // last row of the line, the cursor can sit after its end
+}
+
+type wrapLayout struct {
+	rows      []visualRow
+	lineStart []int // index in rows of the first row of every line
+	width     int
+	usedRows  int
+	version   int // bufferVersion the rows were built for
+	dirty     bool
+}
+
+// layout of the active pane, every pane has its own since their widths differ
+var wrap = &wrapLayout{dirty: true}
+
+// bumped on every edit, lets per buffer caches know they are stale
+var bufferVersion int
+
+// called whenever the text on line y (or the lines after it) changes
+func markBufferChanged(y int) {
+	highlighter.invalidateFrom(y)
+	wrap.dirty = true
+	bufferVersion++
+}
+
+func toggleWordWrap() {
+	settings.WordWrap = !settings.WordWrap
+	for _, p := range panes() {
+		p.scrollX = 0
+		p.scrollY = 0
+	}
+	if settings.WordWrap {
+		editorStatus = "Word wrap on"
+	} else {
+		editorStatus = "Word wrap off"
+	}
+	ensureCursorVisible(cursor)
+}
+
+// column lines are wrapped at, the viewport width unless a smaller wrapColumn is set
+func wrapWidth() int {
+	width := getVisibleCols()
+	if settings.WrapColumn > 0 && settings.WrapColumn < width {
+		width = settings.WrapColumn
+	}
+	if width < 10 {
+		width = 10
+	}
+	return width
+}
+
+func (w *wrapLayout) update() {
+	width := wrapWidth()
+	// the version also catches edits made in another pane showing the buffer
+	if !w.dirty && w.width == width && w.usedRows == usedRows && w.version == bufferVersion {
+		return
+	}
+	w.width = width
+	w.usedRows = usedRows
+	w.version = bufferVersion
+	w.dirty = false
+
+	w.rows = w.rows[:0]
+	if cap(w.lineStart) < usedRows {
+		w.lineStart = make([]int, usedRows)
+	}
+	w.lineStart = w.lineStart[:usedRows]
+
+	for y := 0; y < usedRows; y++ {
+		w.lineStart[y] = len(w.rows)
+		rowWidth := getRowWidth(y)
+		contentLen := len(rowContent(y))
+
+		start := 0
+		for {
+			// keep one column free on the last row for the cursor / line end
+			if contentLen-start < width {
+				w.rows = append(w.rows, visualRow{line: y, start: start, end: rowWidth, first: start == 0, last: true})
+				break
+			}
+
+			// break after the last space that fits, or hard break if there is none
+			brk := start + width
+			for i := start + width - 1; i > start; i-- {
+				if textGrid[y][i] == ' ' {
+					brk = i + 1
+					break
+				}
+			}
+			w.rows = append(w.rows, visualRow{line: y, start: start, end: brk, first: start == 0})
+			start = brk
+		}
+	}
+}
+
+// number of rows the whole document takes on screen
+func totalDisplayRows() int {
+	if !settings.WordWrap {
+		return usedRows
+	}
+	wrap.update()
+	return len(wrap.rows)
+}
+
+// rows currently on screen in the active pane, from the top of its text area down
+func displayRows() []visualRow {
+	visibleRows := getVisibleRows()
+	scrollX, scrollY := activePane.scrollX, activePane.scrollY
+	var rows []visualRow
+
+	if !settings.WordWrap {
+		end := scrollX + getVisibleCols()
+		if maxContentWidth := getMaxContentWidth(); end > maxContentWidth {
+			end = maxContentWidth
+		}
+		for y := scrollY; y < scrollY+visibleRows && y < usedRows; y++ {
+			rows = append(rows, visualRow{line: y, start: scrollX, end: end, first: true, last: true})
+		}
+		return rows
+	}
+
+	wrap.update()
+	for i := scrollY; i < scrollY+visibleRows && i < len(wrap.rows); i++ {
+		rows = append(rows, wrap.rows[i])
+	}
+	return rows
+}
+
+// index of the display row (in the whole document) the cursor is on
+func cursorVisualRow() int {
+	if !settings.WordWrap {
+		return cursor.y
+	}
+	wrap.update()
+	if len(wrap.rows) == 0 {
+		return 0
+	}
+	if cursor.y >= len(wrap.lineStart) {
+		return len(wrap.rows) - 1
+	}
+
+	for i := wrap.lineStart[cursor.y]; i < len(wrap.rows) && wrap.rows[i].line == cursor.y; i++ {
+		if cursor.x < wrap.rows[i].end || wrap.rows[i].last {
+			return i
+		}
+	}
+	return wrap.lineStart[cursor.y]
+}
+
+// true when the cursor is drawn on this row, checks only the column
+func (row visualRow) holdsColumn(x int) bool {
+	if x < row.start {
+		return false
+	}
+	if x < row.end {
+		return true
+	}
+	return row.last && x < row.start+getVisibleCols()
+}
+
+// converts a mouse position to grid coordinates, ok is false outside the text
+func screenToGrid(mouseX, mouseY int) (int, int, bool) {
+	if mouseX < textAreaX() || mouseX >= textAreaRight() || mouseY < textAreaY() {
+		return 0, 0, false
+	}
+	col := (mouseX - textAreaX()) / CHAR_IMAGE_WIDTH
+	row := (mouseY - textAreaY()) / CHAR_IMAGE_HEIGHT
+	if row >= getVisibleRows() {
+		return 0, 0, false
+	}
+
+	if !settings.WordWrap {
+		x := col + activePane.scrollX
+		y := row + activePane.scrollY
+		return x, y, x >= 0 && x < editorCols && y >= 0 && y < editorRows
+	}
+
+	rows := displayRows()
+	if row >= len(rows) {
+		return 0, 0, false
+	}
+	r := rows[row]
+	x := r.start + col
+	if !r.last && x >= r.end {
+		x = r.end - 1
+	}
+	return x, r.line, true
+}
+
+// screen position of a grid cell of the active pane, ok is false when it is not visible
+func gridToScreen(x, y int) (int, int, bool) {
+	for i, row := range displayRows() {
+		if row.line == y && row.holdsColumn(x) {
+			return textAreaX() + (x-row.start)*CHAR_IMAGE_WIDTH, textAreaY() + i*CHAR_IMAGE_HEIGHT, true
+		}
+	}
+	return 0, 0, false
+}
+
+// line shown on the given screen row, used for gutter clicks
+func screenRowLine(row int) (int, bool) {
+	rows := displayRows()
+	if row < 0 || row >= len(rows) {
+		return 0, false
+	}
+	return rows[row].line, true
+}
+
+// moves the cursor up (-1) or down (+1) by one display row, keeping the column inside the row
+func (c *Cursor) moveVisual(dir int) {
+	wrap.update()
+	current := cursorVisualRow()
+	target := current + dir
+	if target < 0 || target >= len(wrap.rows) {
+		return
+	}
+
+	from := wrap.rows[current]
+	to := wrap.rows[target]
+	c.y = to.line
+	c.x = to.start + (c.x - from.start)
+	if !to.last && c.x >= to.end {
+		c.x = to.end - 1
+	}
+	if c.x >= editorCols || textGrid[c.y][c.x] == 0 {
+		c.clampXToLineEnd()
+	}
+	if c.x < to.start {
+		c.x = to.start
+	}
+}
+
+// Home/End inside the current display row
+func (c *Cursor) moveToRowStart() {
+	wrap.update()
+	c.x = wrap.rows[cursorVisualRow()].start
+}
+
+func (c *Cursor) moveToRowEnd() {
+	wrap.update()
+	row := wrap.rows[cursorVisualRow()]
+	if row.last {
+		c.clampXToLineEnd()
+		return
+	}
+	c.x = row.end - 1
+}